@@ -17,16 +17,78 @@ const (
 	// hooks instead.
 	ContainerTypeAnnotationKey = "kubepose.container.type"
 
+	// ServiceExposeGatewayAnnotationKey routes an exposed service through
+	// Gateway API (HTTPRoute, GRPCRoute or TCPRoute) instead of an Ingress.
+	// The value is the parent Gateway as "name" or "namespace/name".
+	ServiceExposeGatewayAnnotationKey = "kubepose.service.expose.gateway"
+	// ServiceExposePortAnnotationKey selects the port an exposed service is
+	// routed to, by compose port name or published port number. Defaults to
+	// the first TCP port.
+	ServiceExposePortAnnotationKey = "kubepose.service.expose.port"
+
+	// ServiceTypeAnnotationKey selects the Kubernetes Service type:
+	// ClusterIP (default), NodePort, LoadBalancer or ExternalName. NodePort
+	// and LoadBalancer services take their node ports from published ports
+	// in the 30000-32767 range.
+	ServiceTypeAnnotationKey = "kubepose.service.type"
+	// ServiceLoadBalancerSourceRangesAnnotationKey restricts a LoadBalancer
+	// Service to a comma or newline separated list of CIDRs.
+	ServiceLoadBalancerSourceRangesAnnotationKey = "kubepose.service.loadBalancerSourceRanges"
+	// ServiceLoadBalancerClassAnnotationKey sets a LoadBalancer Service's
+	// loadBalancerClass.
+	ServiceLoadBalancerClassAnnotationKey = "kubepose.service.loadBalancerClass"
+	// ServiceExternalTrafficPolicyAnnotationKey sets externalTrafficPolicy
+	// (Cluster or Local) on NodePort and LoadBalancer Services.
+	ServiceExternalTrafficPolicyAnnotationKey = "kubepose.service.externalTrafficPolicy"
+
+	// ServiceExtensionKey is the compose service extension holding
+	// structured kubepose settings, e.g. x-kubepose.externalName.
+	ServiceExtensionKey = "x-kubepose"
+
+	// LifecyclePreStopSleepAnnotationKey adds a native preStop sleep action
+	// ("5s" or seconds) so load balancers stop routing to a terminating pod
+	// before it receives its stop signal.
+	LifecyclePreStopSleepAnnotationKey = "kubepose.lifecycle.preStop.sleep"
+
+	// DependsOnAnnotationKey overrides the --depends-on mode per service:
+	// "wait" turns service_started and service_healthy dependencies into
+	// init containers waiting for the dependency's Service, "ignore" drops
+	// depends_on.
+	DependsOnAnnotationKey = "kubepose.dependsOn"
+
 	// CronJobScheduleAnnotationKey, when set on a service, emits a CronJob
 	// using the value as the cron schedule (e.g. "0 * * * *").
 	CronJobScheduleAnnotationKey = "kubepose.cronjob.schedule"
+	// CronJobConcurrencyPolicyAnnotationKey sets whether runs may overlap:
+	// Allow (default), Forbid or Replace.
+	CronJobConcurrencyPolicyAnnotationKey = "kubepose.cronjob.concurrencyPolicy"
+	// CronJobTimeZoneAnnotationKey sets the IANA time zone the schedule is
+	// interpreted in. Defaults to the controller's time zone.
+	CronJobTimeZoneAnnotationKey = "kubepose.cronjob.timeZone"
+	// CronJobSuspendAnnotationKey ("true" or "false") pauses scheduling.
+	CronJobSuspendAnnotationKey = "kubepose.cronjob.suspend"
+	// CronJobStartingDeadlineSecondsAnnotationKey skips runs that could not
+	// start within this many seconds of their scheduled time.
+	CronJobStartingDeadlineSecondsAnnotationKey = "kubepose.cronjob.startingDeadlineSeconds"
+	// CronJobSuccessfulJobsHistoryLimitAnnotationKey and
+	// CronJobFailedJobsHistoryLimitAnnotationKey set how many finished Jobs
+	// are kept.
+	CronJobSuccessfulJobsHistoryLimitAnnotationKey = "kubepose.cronjob.successfulJobsHistoryLimit"
+	CronJobFailedJobsHistoryLimitAnnotationKey     = "kubepose.cronjob.failedJobsHistoryLimit"
+	// CronJobBackoffLimitAnnotationKey sets how often a failed run is
+	// retried before the Job is marked failed.
+	CronJobBackoffLimitAnnotationKey = "kubepose.cronjob.backoffLimit"
+	// CronJobActiveDeadlineSecondsAnnotationKey terminates a run that takes
+	// longer than this many seconds.
+	CronJobActiveDeadlineSecondsAnnotationKey = "kubepose.cronjob.activeDeadlineSeconds"
 
 	// HpaMaxReplicasAnnotationKey, when set on a service, emits an
 	// autoscaling/v2 HorizontalPodAutoscaler targeting the service's
-	// Deployment, scaling on average CPU utilization. The Deployment is
-	// emitted without spec.replicas so re-applies don't reset the HPA's
-	// chosen scale. Requires deploy.resources.reservations.cpus, since
-	// utilization is a percentage of the CPU request.
+	// Deployment, scaling on average CPU utilization unless custom metrics
+	// replace it. The Deployment is emitted without spec.replicas so
+	// re-applies don't reset the HPA's chosen scale. Scaling on CPU requires
+	// deploy.resources.reservations.cpus, since utilization is a percentage
+	// of the CPU request.
 	HpaMaxReplicasAnnotationKey = "kubepose.hpa.maxReplicas"
 	// HpaMinReplicasAnnotationKey sets the HPA's floor. Defaults to
 	// deploy.replicas, else 1.
@@ -34,6 +96,40 @@ const (
 	// HpaCpuAnnotationKey sets the HPA's target average CPU utilization
 	// percentage. Defaults to 80.
 	HpaCpuAnnotationKey = "kubepose.hpa.cpu"
+	// HpaScaleUpStabilizationWindowAnnotationKey and
+	// HpaScaleDownStabilizationWindowAnnotationKey set the HPA behavior's
+	// stabilization window in seconds (0-3600) per direction.
+	HpaScaleUpStabilizationWindowAnnotationKey   = "kubepose.hpa.scaleUp.stabilizationWindowSeconds"
+	HpaScaleDownStabilizationWindowAnnotationKey = "kubepose.hpa.scaleDown.stabilizationWindowSeconds"
+	// HpaScaleUpPoliciesAnnotationKey and HpaScaleDownPoliciesAnnotationKey
+	// set the HPA behavior's scaling policies per direction, as a JSON list
+	// of {"type": "Pods"|"Percent", "value": n, "periodSeconds": n}.
+	HpaScaleUpPoliciesAnnotationKey   = "kubepose.hpa.scaleUp.policies"
+	HpaScaleDownPoliciesAnnotationKey = "kubepose.hpa.scaleDown.policies"
+	// HpaMetricsAnnotationKey adds Pods, Object or External metrics to the
+	// HPA, as a JSON list of autoscaling/v2 MetricSpecs. The same list can be
+	// given as x-kubepose.hpa.metrics.
+	HpaMetricsAnnotationKey = "kubepose.hpa.metrics"
+
+	// VpaUpdateModeAnnotationKey, when set on a service, emits an
+	// autoscaling.k8s.io/v1 VerticalPodAutoscaler for its Deployment or
+	// DaemonSet with this update mode (Off, Initial, Recreate,
+	// InPlaceOrRecreate or Auto), bounded by the compose reservations and
+	// limits.
+	VpaUpdateModeAnnotationKey = "kubepose.vpa.updateMode"
+
+	// GpuResourceNameAnnotationKey overrides the extended resource that GPU
+	// device reservations request, e.g. gpu.intel.com/i915. By default it
+	// follows the device driver: nvidia.com/gpu, or amd.com/gpu for amd.
+	GpuResourceNameAnnotationKey = "kubepose.resources.gpu"
+
+	// ImagePullSecretsAnnotationKey lists, comma separated, Secrets added to
+	// the pod's imagePullSecrets after those given with --image-pull-secret.
+	ImagePullSecretsAnnotationKey = "kubepose.pod.imagePullSecrets"
+
+	// VersionAnnotationKey records the kubepose version that generated an
+	// object.
+	VersionAnnotationKey = "kubepose.version"
 
 	ConfigHmacKeyAnnotationKey     = "kubepose.config.hmacKey"
 	SecretHmacKeyAnnotationKey     = "kubepose.secret.hmacKey"
@@ -42,6 +138,27 @@ const (
 	VolumeStorageClassNameLabelKey = "kubepose.volume.storageClassName"
 	VolumeSizeLabelKey             = "kubepose.volume.size"
 	SecretSubPathLabelKey          = "kubepose.secret.subPath"
+
+	// SecretDecryptLabelKey set to "sops" on a compose secret requires its
+	// file to be SOPS-encrypted. SOPS files are otherwise detected by their
+	// metadata; either way they are decrypted with the age identities in
+	// SOPS_AGE_KEY_FILE before hashing.
+	SecretDecryptLabelKey = "kubepose.secret.decrypt"
+)
+
+// Ownership labels on the content-hashed ConfigMaps and Secrets kubepose
+// generates. They stay the same across content changes, so `kubepose prune`
+// can find the superseded objects of a project.
+const (
+	// ProjectLabelKey holds the compose project name.
+	ProjectLabelKey = "kubepose.project"
+	// ConfigLabelKey holds the compose config name of a config's ConfigMap.
+	ConfigLabelKey = "kubepose.config"
+	// SecretLabelKey holds the compose secret name of a secret's Secret.
+	SecretLabelKey = "kubepose.secret"
+	// VolumeServiceLabelKey holds the service name of the ConfigMap created
+	// for a bind-mounted file.
+	VolumeServiceLabelKey = "kubepose.volume.service"
 )
 
 // HMAC keys allow invalidation if the shape of an immutable
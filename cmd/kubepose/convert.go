@@ -2,12 +2,16 @@ package main
 
 import (
 	"context"
+	"crypto/rsa"
 	"fmt"
+	"net/http"
 	"os"
 	"strings"
+	"time"
 
-	"github.com/middle-management/kubepose"
 	"github.com/middle-management/kubepose/internal/project"
+	"github.com/middle-management/kubepose/internal/registry"
+	"github.com/middle-management/kubepose/internal/sealedsecrets"
 	"github.com/sirupsen/logrus"
 )
 
@@ -15,6 +19,18 @@ type Convert struct {
 	Files    []string `arg:"--file,-f,separate" help:"Compose configuration files"`
 	Profiles []string `arg:"--profile,separate" help:"Specify a compose profile to enable"`
 	LogLevel string   `arg:"--log-level,-l" help:"Log level" default:"info"`
+	Output   string   `arg:"--output,-o" help:"Output format: yaml or json (default from x-kubepose.output, else yaml)"`
+
+	ConversionOptions
+
+	PinDigests  bool   `arg:"--pin-digests" help:"Pin images to the digest their tag resolves to in the registry"`
+	DigestCache string `arg:"--digest-cache" help:"JSON file caching resolved digests between runs"`
+	Offline     bool   `arg:"--offline" help:"Resolve digests from --digest-cache only"`
+
+	SealWith        string   `arg:"--seal-with" help:"Seal generated Secrets into SealedSecrets with this sealed-secrets certificate (PEM)"`
+	SealNamespace   string   `arg:"--seal-namespace" help:"Seal Secrets in strict scope for this namespace (default: x-kubepose.namespace)"`
+	SealClusterWide bool     `arg:"--seal-cluster-wide" help:"Seal Secrets cluster-wide, so they unseal in any namespace"`
+	SopsAge         []string `arg:"--sops-age,separate" help:"SOPS-encrypt generated Secrets for this age recipient"`
 }
 
 func (cmd *Convert) Run() error {
@@ -22,6 +38,33 @@ func (cmd *Convert) Run() error {
 		logrus.SetLevel(level)
 	}
 
+	transformer, err := cmd.transformer()
+	if err != nil {
+		return err
+	}
+
+	if cmd.Offline && (!cmd.PinDigests || cmd.DigestCache == "") {
+		return fmt.Errorf("--offline requires --pin-digests and --digest-cache")
+	}
+
+	if cmd.SealNamespace != "" && cmd.SealWith == "" {
+		return fmt.Errorf("--seal-namespace requires --seal-with")
+	}
+	if cmd.SealClusterWide && cmd.SealWith == "" {
+		return fmt.Errorf("--seal-cluster-wide requires --seal-with")
+	}
+	var sealPublicKey *rsa.PublicKey
+	if cmd.SealWith != "" {
+		cert, err := os.ReadFile(cmd.SealWith)
+		if err != nil {
+			return fmt.Errorf("unable to read --seal-with certificate: %w", err)
+		}
+		sealPublicKey, err = sealedsecrets.ParsePublicKey(cert)
+		if err != nil {
+			return fmt.Errorf("invalid --seal-with certificate %s: %w", cmd.SealWith, err)
+		}
+	}
+
 	project, err := project.New(context.Background(), project.Options{
 		Files:    cmd.Files,
 		Profiles: cmd.Profiles,
@@ -37,13 +80,22 @@ func (cmd *Convert) Run() error {
 		}).Warn("Some services were disabled because profiles did not match")
 	}
 
-	transformer := kubepose.Transformer{
-		Annotations: map[string]string{
-			"kubepose.version": getVersion(),
-		},
-		Labels: map[string]string{
-			"app.kubernetes.io/managed-by": "kubepose",
-		},
+	transformer.SealPublicKey = sealPublicKey
+	transformer.SealNamespace = cmd.SealNamespace
+	transformer.SealClusterWide = cmd.SealClusterWide
+	transformer.SopsAgeRecipients = cmd.SopsAge
+
+	var resolver *registry.Resolver
+	if cmd.PinDigests {
+		resolver = &registry.Resolver{
+			Client:    &http.Client{Timeout: 30 * time.Second},
+			CacheFile: cmd.DigestCache,
+			Offline:   cmd.Offline,
+		}
+		if err := resolver.Load(); err != nil {
+			return err
+		}
+		transformer.DigestResolver = resolver
 	}
 
 	resources, err := transformer.Convert(project)
@@ -51,6 +103,15 @@ func (cmd *Convert) Run() error {
 		return fmt.Errorf("unable to convert: %w", err)
 	}
 
+	if resolver != nil {
+		if err := resolver.Save(); err != nil {
+			return err
+		}
+	}
+
+	if cmd.Output != "" {
+		resources.Format = cmd.Output
+	}
 	err = resources.Write(os.Stdout)
 	if err != nil {
 		return fmt.Errorf("unable to write resources to file: %w", err)
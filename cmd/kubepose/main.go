@@ -3,6 +3,9 @@ package main
 import (
 	"log"
 	"os"
+	// Embedded so kubepose.cronjob.timeZone validates on hosts without
+	// zoneinfo, such as scratch images.
+	_ "time/tzdata"
 
 	"github.com/alexflint/go-arg"
 )
@@ -17,6 +20,8 @@ func main() {
 		switch {
 		case args.Convert != nil:
 			return args.Convert.Run()
+		case args.Prune != nil:
+			return args.Prune.Run()
 		case args.Version != nil:
 			return args.Version.Run()
 		default:
@@ -31,5 +36,6 @@ func main() {
 
 type Main struct {
 	Convert *Convert `arg:"subcommand:convert" help:"Convert compose spec to kubernetes resources"`
+	Prune   *Prune   `arg:"subcommand:prune" help:"List or delete superseded generated ConfigMaps and Secrets"`
 	Version *Version `arg:"subcommand:version" help:"Command version"`
 }
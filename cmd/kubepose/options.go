@@ -0,0 +1,57 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/middle-management/kubepose"
+)
+
+// ConversionOptions are the flags that shape a conversion. convert and prune
+// share them, so prune validates and names objects exactly as convert did.
+type ConversionOptions struct {
+	IngressAPI string `arg:"--ingress-api" help:"API used for kubepose.service.expose: ingress or gateway" default:"ingress"`
+	Gateway    string `arg:"--gateway" help:"Default parent Gateway (name or namespace/name) for Gateway API routes"`
+
+	DependsOn   string `arg:"--depends-on" help:"How depends_on is converted: ignore, or wait for dependencies in init containers" default:"ignore"`
+	KubeVersion string `arg:"--kube-version" help:"Target Kubernetes version (e.g. 1.34); enables fields older clusters would drop"`
+
+	ImagePullSecrets []string `arg:"--image-pull-secret,separate" help:"Secret added to every pod's imagePullSecrets"`
+	RegistryMirrors  []string `arg:"--registry-mirror,separate" help:"Rewrite image references from one registry or repository prefix to another (from=to)"`
+
+	RequireExternalSecrets bool `arg:"--require-external-secrets" help:"Mount external secrets as required volumes, so pods wait for missing Secrets instead of getting empty files"`
+
+	KeepControlAnnotations bool `arg:"--keep-control-annotations" help:"Keep the kubepose.* annotations and labels that steer the conversion on generated objects"`
+}
+
+// transformer builds the Transformer for the options.
+func (o ConversionOptions) transformer() (kubepose.Transformer, error) {
+	registryMirrors := make(map[string]string, len(o.RegistryMirrors))
+	for _, mirror := range o.RegistryMirrors {
+		from, to, ok := strings.Cut(mirror, "=")
+		if !ok {
+			return kubepose.Transformer{}, fmt.Errorf("invalid --registry-mirror %q: expected from=to", mirror)
+		}
+		registryMirrors[from] = to
+	}
+
+	return kubepose.Transformer{
+		Annotations: map[string]string{
+			kubepose.VersionAnnotationKey: getVersion(),
+		},
+		Labels: map[string]string{
+			"app.kubernetes.io/managed-by": "kubepose",
+		},
+		IngressAPI:  o.IngressAPI,
+		Gateway:     o.Gateway,
+		KubeVersion: o.KubeVersion,
+		DependsOn:   o.DependsOn,
+
+		ImagePullSecrets: o.ImagePullSecrets,
+		RegistryMirrors:  registryMirrors,
+
+		RequireExternalSecrets: o.RequireExternalSecrets,
+
+		KeepControlAnnotations: o.KeepControlAnnotations,
+	}, nil
+}
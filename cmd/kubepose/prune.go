@@ -0,0 +1,89 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"strings"
+
+	"github.com/middle-management/kubepose"
+	"github.com/middle-management/kubepose/internal/project"
+)
+
+type Prune struct {
+	Files    []string `arg:"--file,-f,separate" help:"Compose configuration files"`
+	Profiles []string `arg:"--profile,separate" help:"Specify a compose profile to enable"`
+
+	Live      string `arg:"--live" help:"kubectl get -o json output of the live ConfigMaps and Secrets, or - for stdin" default:"-"`
+	Namespace string `arg:"--namespace,-n" help:"Namespace to prune (default from x-kubepose.namespace, else the namespace of the live objects)"`
+	Delete    bool   `arg:"--delete" help:"Delete the stale objects with kubectl instead of printing them"`
+
+	// Options that change validation or generated names must match the
+	// convert run, so prune takes the same ones.
+	ConversionOptions
+}
+
+func (cmd *Prune) Run() error {
+	transformer, err := cmd.transformer()
+	if err != nil {
+		return err
+	}
+
+	project, err := project.New(context.Background(), project.Options{
+		Files:    cmd.Files,
+		Profiles: cmd.Profiles,
+	})
+	if err != nil {
+		return fmt.Errorf("unable to load files: %w", err)
+	}
+
+	// Sealing and digest pinning are left out: a SealedSecret is current
+	// whenever its Secret is, and digests do not affect object names.
+	resources, err := transformer.Convert(project)
+	if err != nil {
+		return fmt.Errorf("unable to convert: %w", err)
+	}
+
+	var live io.Reader = os.Stdin
+	if cmd.Live != "-" {
+		file, err := os.Open(cmd.Live)
+		if err != nil {
+			return fmt.Errorf("unable to read live objects: %w", err)
+		}
+		defer file.Close()
+		live = file
+	}
+
+	stale, err := kubepose.FindStaleObjects(resources, project.Name, cmd.Namespace, live)
+	if err != nil {
+		return err
+	}
+
+	if !cmd.Delete {
+		for _, object := range stale {
+			fmt.Println(object)
+		}
+		return nil
+	}
+
+	if len(stale) == 0 {
+		return nil
+	}
+	// FindStaleObjects only returns objects of a single namespace.
+	args := []string{"delete"}
+	if namespace := stale[0].Namespace; namespace != "" {
+		args = append(args, "--namespace", namespace)
+	}
+	for _, object := range stale {
+		args = append(args, strings.ToLower(object.Kind)+"/"+object.Name)
+	}
+	kubectl := exec.Command("kubectl", args...)
+	kubectl.Stdout = os.Stdout
+	kubectl.Stderr = os.Stderr
+	if err := kubectl.Run(); err != nil {
+		return fmt.Errorf("kubectl delete: %w", err)
+	}
+	return nil
+}
@@ -7,6 +7,7 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"unicode/utf8"
 
 	"github.com/compose-spec/compose-go/v2/types"
 	corev1 "k8s.io/api/core/v1"
@@ -14,6 +15,30 @@ import (
 	"k8s.io/utils/ptr"
 )
 
+// maxObjectBytes is the size limit the API server (through etcd) puts on a
+// single ConfigMap or Secret.
+const maxObjectBytes = 1 << 20
+
+// checkObjectSize rejects content that cannot fit in one ConfigMap or Secret,
+// which the API server would otherwise refuse at apply time.
+func checkObjectSize(content []byte) error {
+	if len(content) > maxObjectBytes {
+		return fmt.Errorf("%d bytes exceeds the 1MiB limit of a Kubernetes object", len(content))
+	}
+	return nil
+}
+
+// setConfigMapContent stores content under key in Data when it is UTF-8 text
+// and in BinaryData otherwise; Data only holds strings, so binary files such
+// as keystores would be corrupted or rejected there.
+func setConfigMapContent(configMap *corev1.ConfigMap, key string, content []byte) {
+	if utf8.Valid(content) {
+		configMap.Data = map[string]string{key: string(content)}
+		return
+	}
+	configMap.BinaryData = map[string][]byte{key: content}
+}
+
 type ConfigMapping struct {
 	Name     string
 	Key      string
@@ -61,6 +86,13 @@ func (t Transformer) processConfigs(project *types.Project, resources *Resources
 			return nil, fmt.Errorf("config %s must specify either content, file or environment", name)
 		}
 
+		if err := checkObjectSize(content); err != nil {
+			if config.File != "" {
+				return nil, fmt.Errorf("config %s (file %s): %w", name, config.File, err)
+			}
+			return nil, fmt.Errorf("config %s: %w", name, err)
+		}
+
 		k8sConfigName := fmt.Sprintf("%s-%s", name, shortHash)
 		configMapping[name] = ConfigMapping{Name: k8sConfigName, Key: filename}
 
@@ -70,17 +102,18 @@ func (t Transformer) processConfigs(project *types.Project, resources *Resources
 				Kind:       "ConfigMap",
 			},
 			ObjectMeta: metav1.ObjectMeta{
-				Name:   k8sConfigName,
-				Labels: mergeMaps(config.Labels, t.Labels),
+				Name: k8sConfigName,
+				Labels: mergeMaps(config.Labels, t.Labels, map[string]string{
+					ProjectLabelKey: project.Name,
+					ConfigLabelKey:  name,
+				}),
 				Annotations: mergeMaps(t.Annotations, map[string]string{
 					ConfigHmacKeyAnnotationKey: configHmacKey,
 				}),
 			},
 			Immutable: ptr.To(true),
-			Data: map[string]string{
-				filename: string(content),
-			},
 		}
+		setConfigMapContent(&k8sConfigMap, filename, content)
 
 		resources.ConfigMaps = append(resources.ConfigMaps, &k8sConfigMap)
 	}
@@ -107,8 +140,11 @@ func (t Transformer) updatePodSpecWithConfigs(spec *corev1.PodSpec, service type
 				target = "/" + serviceConfig.Source
 			}
 
+			ref := types.FileReferenceConfig(serviceConfig)
+			warnFileOwnership(spec, service, "config", ref)
+
 			// Create volume if it doesn't already exist
-			volumeName := serviceConfig.Source
+			volumeName := fileReferenceVolumeName(ref)
 			volumeExists := false
 			for _, v := range spec.Volumes {
 				if v.Name == volumeName {
@@ -136,8 +172,11 @@ func (t Transformer) updatePodSpecWithConfigs(spec *corev1.PodSpec, service type
 						{
 							Key:  mapping.Key,
 							Path: filepath.Base(target),
+							Mode: fileReferenceMode(ref),
 						},
 					}
+				} else {
+					volume.VolumeSource.ConfigMap.DefaultMode = fileReferenceMode(ref)
 				}
 
 				spec.Volumes = append(spec.Volumes, volume)
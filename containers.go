@@ -3,11 +3,12 @@ package kubepose
 import (
 	"fmt"
 	"sort"
+	"strconv"
 	"strings"
 
 	"github.com/compose-spec/compose-go/v2/types"
+	"github.com/sirupsen/logrus"
 	corev1 "k8s.io/api/core/v1"
-	"k8s.io/apimachinery/pkg/api/resource"
 	"k8s.io/utils/ptr"
 )
 
@@ -23,13 +24,13 @@ func (t Transformer) createContainer(service types.ServiceConfig) corev1.Contain
 	}
 	return corev1.Container{
 		Name:            service.Name,
-		Image:           service.Image,
+		Image:           t.rewriteImage(service.Image),
 		Command:         service.Entrypoint,
 		WorkingDir:      service.WorkingDir,
 		Stdin:           service.StdinOpen,
 		TTY:             service.Tty,
 		Args:            escapeEnvs(service.Command),
-		Ports:           convertPorts(service.Ports),
+		Ports:           convertPorts(service),
 		Env:             convertEnvironment(service.Environment),
 		Resources:       getResourceRequirements(service),
 		ImagePullPolicy: getImagePullPolicy(service),
@@ -37,6 +38,7 @@ func (t Transformer) createContainer(service types.ServiceConfig) corev1.Contain
 		ReadinessProbe:  readinessProbe,
 		StartupProbe:    startupProbe,
 		RestartPolicy:   containerRestartPolicy,
+		Lifecycle:       t.getLifecycle(service),
 	}
 }
 
@@ -65,7 +67,7 @@ func (t Transformer) createPreStartContainers(service types.ServiceConfig) []cor
 
 		containers = append(containers, corev1.Container{
 			Name:            preStartContainerName(service, i),
-			Image:           image,
+			Image:           t.rewriteImage(image),
 			Command:         escapeEnvs(hook.Command),
 			WorkingDir:      hook.WorkingDir,
 			Env:             convertEnvironment(env),
@@ -116,13 +118,39 @@ func inheritPreStartVolumeMounts(spec *corev1.PodSpec, service types.ServiceConf
 	}
 }
 
-func convertPorts(ports []types.ServicePortConfig) []corev1.ContainerPort {
+// convertPorts converts compose ports into container ports. host_ip bindings
+// become hostIP/hostPort on DaemonSets, where one pod per node keeps the host
+// port free of conflicts; elsewhere they are dropped with a warning, since
+// replicas scheduled onto the same node would collide.
+func convertPorts(service types.ServiceConfig) []corev1.ContainerPort {
+	isDaemonSet := service.Deploy != nil && service.Deploy.Mode == "global"
+	seen := make(map[corev1.ContainerPort]bool)
 	var containerPorts []corev1.ContainerPort
-	for _, port := range ports {
-		containerPorts = append(containerPorts, corev1.ContainerPort{
+	for _, port := range service.Ports {
+		containerPort := corev1.ContainerPort{
+			Name:          port.Name,
 			ContainerPort: int32(port.Target),
 			Protocol:      convertProtocol(port.Protocol),
-		})
+		}
+		if port.HostIP != "" {
+			if isDaemonSet {
+				hostPort := int(port.Target)
+				if port.Published != "" {
+					hostPort, _ = strconv.Atoi(port.Published)
+				}
+				containerPort.HostPort = int32(hostPort)
+				containerPort.HostIP = port.HostIP
+			} else {
+				logrus.Warnf("service %q: host_ip %s on port %d is only mapped for deploy.mode: global", service.Name, port.HostIP, port.Target)
+			}
+		}
+		// Several published ports (e.g. an expanded range) may forward to
+		// the same container port, which only needs declaring once.
+		if seen[containerPort] {
+			continue
+		}
+		seen[containerPort] = true
+		containerPorts = append(containerPorts, containerPort)
 	}
 	return containerPorts
 }
@@ -144,27 +172,6 @@ func convertEnvironment(env map[string]*string) []corev1.EnvVar {
 	return envVars
 }
 
-func getResourceRequirements(service types.ServiceConfig) corev1.ResourceRequirements {
-	resources := corev1.ResourceRequirements{}
-
-	if service.Deploy != nil {
-		if service.Deploy.Resources.Limits != nil {
-			resources.Limits = corev1.ResourceList{
-				corev1.ResourceCPU:    resource.MustParse(fmt.Sprintf("%dm", int(service.Deploy.Resources.Limits.NanoCPUs.Value()*1000))),
-				corev1.ResourceMemory: resource.MustParse(fmt.Sprintf("%dMi", service.Deploy.Resources.Limits.MemoryBytes/1024/1024)),
-			}
-		}
-		if service.Deploy.Resources.Reservations != nil {
-			resources.Requests = corev1.ResourceList{
-				corev1.ResourceCPU:    resource.MustParse(fmt.Sprintf("%dm", int(service.Deploy.Resources.Reservations.NanoCPUs.Value()*1000))),
-				corev1.ResourceMemory: resource.MustParse(fmt.Sprintf("%dMi", service.Deploy.Resources.Reservations.MemoryBytes/1024/1024)),
-			}
-		}
-	}
-
-	return resources
-}
-
 func getImagePullPolicy(service types.ServiceConfig) corev1.PullPolicy {
 	if service.PullPolicy == "" {
 		return corev1.PullIfNotPresent // default behavior
@@ -0,0 +1,75 @@
+package kubepose
+
+import (
+	"strings"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// controlKeyPrefix prefixes the annotations and labels that steer the
+// conversion, such as kubepose.hpa.maxReplicas or kubepose.volume.size.
+const controlKeyPrefix = "kubepose."
+
+// identityKeys are the kubepose annotations and labels that describe a
+// generated object rather than steer the conversion; they are kept in the
+// output.
+var identityKeys = map[string]bool{
+	VersionAnnotationKey:       true,
+	ConfigHmacKeyAnnotationKey: true,
+	SecretHmacKeyAnnotationKey: true,
+	VolumeHmacKeyAnnotationKey: true,
+	ProjectLabelKey:            true,
+	ConfigLabelKey:             true,
+	SecretLabelKey:             true,
+	VolumeServiceLabelKey:      true,
+}
+
+// stripControlMetadata removes the kubepose control annotations and labels
+// from the generated objects and their pod templates. Left in place they
+// add noise, and changing one would roll the pods.
+func stripControlMetadata(resources *Resources) {
+	for _, meta := range generatedMetadata(resources) {
+		meta.SetAnnotations(withoutControlKeys(meta.GetAnnotations()))
+		meta.SetLabels(withoutControlKeys(meta.GetLabels()))
+	}
+}
+
+func withoutControlKeys(values map[string]string) map[string]string {
+	if len(values) == 0 {
+		return values
+	}
+	kept := map[string]string{}
+	for key, value := range values {
+		if !strings.HasPrefix(key, controlKeyPrefix) || identityKeys[key] {
+			kept[key] = value
+		}
+	}
+	if len(kept) == 0 {
+		return nil
+	}
+	return kept
+}
+
+// generatedMetadata returns the metadata of every generated object and of
+// the pod and job templates embedded in them.
+func generatedMetadata(resources *Resources) []metav1.Object {
+	var metas []metav1.Object
+	for _, object := range resources.objects() {
+		metas = append(metas, object)
+	}
+	for _, deployment := range resources.Deployments {
+		metas = append(metas, &deployment.Spec.Template.ObjectMeta)
+	}
+	for _, ds := range resources.DaemonSets {
+		metas = append(metas, &ds.Spec.Template.ObjectMeta)
+	}
+	for _, cronJob := range resources.CronJobs {
+		metas = append(metas, &cronJob.Spec.JobTemplate.ObjectMeta, &cronJob.Spec.JobTemplate.Spec.Template.ObjectMeta)
+	}
+	for _, scaledJob := range resources.ScaledJobs {
+		if scaledJob.Spec.JobTargetRef != nil {
+			metas = append(metas, &scaledJob.Spec.JobTargetRef.Template.ObjectMeta)
+		}
+	}
+	return metas
+}
@@ -1,6 +1,7 @@
 package kubepose
 
 import (
+	"crypto/rsa"
 	"fmt"
 	"regexp"
 	"sort"
@@ -9,21 +10,142 @@ import (
 
 	"github.com/compose-spec/compose-go/v2/types"
 	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/util/validation"
 )
 
 type Transformer struct {
 	Annotations map[string]string
 	Labels      map[string]string
+
+	// IngressAPI selects how kubepose.service.expose is emitted:
+	// IngressAPIIngress (the default when empty) or IngressAPIGateway.
+	IngressAPI string
+	// Gateway is the default parent Gateway ("name" or "namespace/name") for
+	// Gateway API routes, overridden per service by
+	// kubepose.service.expose.gateway.
+	Gateway string
+	// KubeVersion is the target cluster's Kubernetes version ("1.34"). It
+	// gates fields that older clusters would silently drop; empty means the
+	// most portable output.
+	KubeVersion string
+	// DependsOn selects how depends_on is converted: DependsOnIgnore (the
+	// default when empty) or DependsOnWait. Overridden per service by
+	// kubepose.dependsOn.
+	DependsOn string
+	// ImagePullSecrets names Secrets added to every pod's imagePullSecrets,
+	// ahead of any from kubepose.pod.imagePullSecrets.
+	ImagePullSecrets []string
+	// RegistryMirrors rewrites image references whose normalized name
+	// starts with a key ("docker.io", "ghcr.io/org") to start with its
+	// value instead.
+	RegistryMirrors map[string]string
+	// RequireExternalSecrets mounts external compose secrets as required
+	// Secret volumes, so a missing Secret keeps the pod from starting
+	// instead of leaving an empty file.
+	RequireExternalSecrets bool
+	// DigestResolver, when set, pins every image to the digest its tag
+	// resolves to.
+	DigestResolver DigestResolver
+	// SealPublicKey, when set, seals every generated Secret into a Bitnami
+	// SealedSecret for the controller holding the matching private key.
+	SealPublicKey *rsa.PublicKey
+	// SealNamespace seals Secrets in strict scope for this namespace; empty
+	// uses x-kubepose.namespace.
+	SealNamespace string
+	// SealClusterWide seals Secrets cluster-wide, so they unseal under any
+	// name in any namespace. Without it, sealing requires a namespace.
+	SealClusterWide bool
+	// SopsAgeRecipients, when set, SOPS-encrypts every generated Secret's
+	// data for these age recipients instead. Mutually exclusive with
+	// SealPublicKey.
+	SopsAgeRecipients []string
+	// KeepControlAnnotations keeps the kubepose.* annotations and labels
+	// that steer the conversion on the generated objects. By default only
+	// the identity keys, such as kubepose.version and the hmac keys, are
+	// kept.
+	KeepControlAnnotations bool
 }
 
 func (t Transformer) Convert(project *types.Project) (*Resources, error) {
+	ext, err := getProjectExtension(project)
+	if err != nil {
+		return nil, err
+	}
+	// Service-level x-kubepose fields take precedence over the project
+	// defaults, like the annotations they alias.
+	project, err = applyServiceExtensions(project)
+	if err != nil {
+		return nil, err
+	}
+	project = ext.applyDefaults(project)
+	if len(ext.CommonLabels) > 0 {
+		t.Labels = mergeMaps(ext.CommonLabels, t.Labels)
+	}
+
+	switch t.IngressAPI {
+	case "", IngressAPIIngress, IngressAPIGateway:
+	default:
+		return nil, fmt.Errorf("unsupported ingress API %q (expected %q or %q)", t.IngressAPI, IngressAPIIngress, IngressAPIGateway)
+	}
+	switch t.DependsOn {
+	case "", DependsOnIgnore, DependsOnWait:
+	default:
+		return nil, fmt.Errorf("unsupported depends_on mode %q (expected %q or %q)", t.DependsOn, DependsOnIgnore, DependsOnWait)
+	}
+	if t.KubeVersion != "" {
+		if _, err := parseKubeVersion(t.KubeVersion); err != nil {
+			return nil, err
+		}
+	}
+	for _, name := range t.ImagePullSecrets {
+		if errs := validation.IsDNS1123Subdomain(name); len(errs) > 0 {
+			return nil, fmt.Errorf("image pull secret %q: %s", name, strings.Join(errs, "; "))
+		}
+	}
+	if err := t.validateRegistryMirrors(); err != nil {
+		return nil, err
+	}
+	if t.SealPublicKey != nil && len(t.SopsAgeRecipients) > 0 {
+		return nil, fmt.Errorf("secrets can be sealed or SOPS-encrypted, not both")
+	}
+	if t.SealNamespace != "" {
+		if errs := validation.IsDNS1123Label(t.SealNamespace); len(errs) > 0 {
+			return nil, fmt.Errorf("seal namespace %q: %s", t.SealNamespace, strings.Join(errs, "; "))
+		}
+		if t.SealClusterWide {
+			return nil, fmt.Errorf("secrets can be sealed for a namespace or cluster-wide, not both")
+		}
+		// A Secret sealed for another namespace than the one it is
+		// deployed to never unseals.
+		if ext.Namespace != "" && ext.Namespace != t.SealNamespace {
+			return nil, fmt.Errorf("seal namespace %q differs from %s.namespace %q", t.SealNamespace, ServiceExtensionKey, ext.Namespace)
+		}
+	}
+
 	for _, name := range project.ServiceNames() {
 		if err := validateService(project.Services[name]); err != nil {
 			return nil, fmt.Errorf("service %q: %w", name, err)
 		}
+		if err := t.validateGateway(project.Services[name]); err != nil {
+			return nil, fmt.Errorf("service %q: %w", name, err)
+		}
+		if err := t.validateStopSignal(project.Services[name]); err != nil {
+			return nil, fmt.Errorf("service %q: %w", name, err)
+		}
+		if err := t.validateDependsOn(project, project.Services[name]); err != nil {
+			return nil, fmt.Errorf("service %q: %w", name, err)
+		}
+	}
+	project, err = groupSharedNamespaces(project)
+	if err != nil {
+		return nil, err
 	}
+	if err := t.validateStopSignalPID(project); err != nil {
+		return nil, err
+	}
+	project = expandPortRanges(project)
 
-	resources := &Resources{}
+	resources := &Resources{Format: ext.Output}
 
 	secretMappings, err := t.processSecrets(project, resources)
 	if err != nil {
@@ -57,15 +179,30 @@ func (t Transformer) Convert(project *types.Project) (*Resources, error) {
 	// Group services by kubepose.service.group
 	groups := make(map[string][]types.ServiceConfig)
 	for _, service := range project.Services {
+		// ExternalName services stand in for something running outside the
+		// cluster, so they get a Service and no workload.
+		if getServiceType(service) == corev1.ServiceTypeExternalName {
+			resources.Services = append(resources.Services, t.createService(service))
+			continue
+		}
+
 		// Handle standalone pods (non-Always restart policy)
 		if _, isCronJob := service.Annotations[CronJobScheduleAnnotationKey]; !isCronJob && getRestartPolicy(service) != corev1.RestartPolicyAlways {
 			pod := t.createPod(service)
 			pod.Spec.InitContainers = t.createPreStartContainers(service)
+			t.addDependsOnWaitContainers(&pod.Spec, project, []types.ServiceConfig{service})
 			pod.Spec.Containers = []corev1.Container{t.createContainer(service)}
+			setPodOSForStopSignals(&pod.Spec)
 			t.updatePodSpecWithSecrets(&pod.Spec, service, secretMappings)
 			t.updatePodSpecWithConfigs(&pod.Spec, service, configMappings)
-			t.updatePodSpecWithVolumes(&pod.Spec, service, volumeMappings, resources)
+			if err := t.updatePodSpecWithVolumes(&pod.Spec, project, service, volumeMappings, resources); err != nil {
+				return nil, fmt.Errorf("service %q: %w", service.Name, err)
+			}
 			inheritPreStartVolumeMounts(&pod.Spec, service)
+			if hasKeda(service) {
+				t.createScaledJob(resources, service, pod.Spec)
+				continue
+			}
 			resources.Pods = append(resources.Pods, pod)
 			continue
 		}
@@ -114,30 +251,47 @@ func (t Transformer) Convert(project *types.Project) (*Resources, error) {
 
 		for _, service := range appServices {
 			var podSpec *corev1.PodSpec
+			var workloadKind string
 			if _, ok := service.Annotations[CronJobScheduleAnnotationKey]; ok {
 				cj := t.createCronJob(resources, service)
 				podSpec = &cj.Spec.JobTemplate.Spec.Template.Spec
 			} else if service.Deploy != nil && service.Deploy.Mode == "global" {
 				ds := t.createDaemonSet(resources, service)
 				podSpec = &ds.Spec.Template.Spec
+				workloadKind = "DaemonSet"
 			} else {
 				deploy := t.createDeployment(resources, service)
 				podSpec = &deploy.Spec.Template.Spec
-				if hasHorizontalPodAutoscaler(service) {
-					// The HPA owns the replica count; a pinned spec.replicas
-					// would reset its chosen scale on every apply. Cleared
-					// here rather than in createDeployment so it also covers
-					// a grouped service whose Deployment was created first
-					// by another member.
+				workloadKind = "Deployment"
+				if hasHorizontalPodAutoscaler(service) || hasKeda(service) {
+					// The HPA (or KEDA's) owns the replica count; a pinned
+					// spec.replicas would reset its chosen scale on every
+					// apply. Cleared here rather than in createDeployment so
+					// it also covers a grouped service whose Deployment was
+					// created first by another member.
 					deploy.Spec.Replicas = nil
-					t.createHorizontalPodAutoscaler(resources, service)
+					if hasKeda(service) {
+						t.createScaledObject(resources, service)
+					} else {
+						t.createHorizontalPodAutoscaler(resources, service)
+					}
 				}
 			}
-			t.addContainersToSpec(podSpec, appServices, initServices)
+			if err := t.addContainersToSpec(podSpec, appServices, initServices); err != nil {
+				return nil, fmt.Errorf("group %q: %w", groupName, err)
+			}
+			if hasVerticalPodAutoscaler(service) {
+				// After addContainersToSpec, so the VPA bounds every
+				// container of the group's pod.
+				t.createVerticalPodAutoscaler(resources, service, workloadKind, podSpec)
+			}
+			t.addDependsOnWaitContainers(podSpec, project, append(appServices, initServices...))
 			for _, svc := range append(appServices, initServices...) {
 				t.updatePodSpecWithSecrets(podSpec, svc, secretMappings)
 				t.updatePodSpecWithConfigs(podSpec, svc, configMappings)
-				t.updatePodSpecWithVolumes(podSpec, svc, volumeMappings, resources)
+				if err := t.updatePodSpecWithVolumes(podSpec, project, svc, volumeMappings, resources); err != nil {
+					return nil, fmt.Errorf("service %q: %w", svc.Name, err)
+				}
 			}
 			for _, svc := range append(appServices, initServices...) {
 				inheritPreStartVolumeMounts(podSpec, svc)
@@ -162,10 +316,18 @@ func (t Transformer) Convert(project *types.Project) (*Resources, error) {
 				if existing != nil {
 					// Another group member created the Service first; fold in
 					// this member's ports so declaration order doesn't matter.
-					mergeServicePorts(existing, svc.Spec.Ports)
+					if err := mergeServicePorts(existing, svc); err != nil {
+						return nil, fmt.Errorf("group %q: %w", groupName, err)
+					}
 				} else {
 					resources.Services = append(resources.Services, svc)
 					if _, ok := service.Annotations[ServiceExposeAnnotationKey]; ok {
+						if t.usesGatewayAPI(service) {
+							if err := t.createGatewayRoute(resources, service); err != nil {
+								return nil, fmt.Errorf("service %q: %w", service.Name, err)
+							}
+							continue
+						}
 						ingress := t.createIngress(service)
 						if ingress == nil {
 							continue
@@ -177,6 +339,30 @@ func (t Transformer) Convert(project *types.Project) (*Resources, error) {
 		}
 	}
 
+	if err := t.createExternalLinkServices(project, resources); err != nil {
+		return nil, err
+	}
+
+	if t.DigestResolver != nil {
+		if err := t.pinDigests(resources); err != nil {
+			return nil, err
+		}
+	}
+
+	if !t.KeepControlAnnotations {
+		stripControlMetadata(resources)
+	}
+
+	if ext.Namespace != "" {
+		for _, object := range resources.objects() {
+			object.SetNamespace(ext.Namespace)
+		}
+	}
+
+	if err := t.encryptSecrets(resources); err != nil {
+		return nil, err
+	}
+
 	return resources, nil
 }
 
@@ -195,19 +381,26 @@ func validateService(service types.ServiceConfig) error {
 			return fmt.Errorf("unsupported healthcheck test type %q (expected CMD, CMD-SHELL, or NONE)", service.HealthCheck.Test[0])
 		}
 	}
-	for _, port := range service.Ports {
-		if port.Published == "" {
-			continue
-		}
-		if _, err := strconv.Atoi(port.Published); err != nil {
-			return fmt.Errorf("invalid published port %q: only single numeric ports are supported", port.Published)
-		}
+	if err := validatePorts(service); err != nil {
+		return err
 	}
-	for _, e := range service.Expose {
-		target, _, _ := strings.Cut(e, "/")
-		if _, err := strconv.Atoi(target); err != nil {
-			return fmt.Errorf(`invalid expose entry %q: only "port" or "port/protocol" is supported`, e)
-		}
+	if err := validateDNS(service); err != nil {
+		return err
+	}
+	if err := validateNamespaces(service); err != nil {
+		return err
+	}
+	if err := validateResources(service); err != nil {
+		return err
+	}
+	if err := validateSysctls(service); err != nil {
+		return err
+	}
+	if err := validateImagePullSecrets(service); err != nil {
+		return err
+	}
+	if err := validateFileReferences(service); err != nil {
+		return err
 	}
 	// Named users and groups resolve against the image's /etc/passwd locally
 	// but cannot be mapped to Kubernetes securityContext IDs; silently
@@ -220,13 +413,16 @@ func validateService(service types.ServiceConfig) error {
 			return fmt.Errorf("group_add %q: only numeric group IDs are supported on Kubernetes", g)
 		}
 	}
+	if err := validateLifecycleHooks(service); err != nil {
+		return err
+	}
 	for i, hook := range service.PreStart {
 		if err := validateNumericUserGroup(hook.User); err != nil {
 			return fmt.Errorf("pre_start hook %d: %w", i, err)
 		}
 	}
-	if schedule, ok := service.Annotations[CronJobScheduleAnnotationKey]; ok && schedule == "" {
-		return fmt.Errorf("%s must not be empty", CronJobScheduleAnnotationKey)
+	if err := validateCronJob(service); err != nil {
+		return err
 	}
 	// An init-typed service exists to become a native sidecar: an
 	// initContainers entry with container-level restartPolicy Always.
@@ -254,7 +450,16 @@ func validateService(service types.ServiceConfig) error {
 			return fmt.Errorf("%s requires restart: always (the service converts to a standalone Pod, which cannot be autoscaled)", HpaMaxReplicasAnnotationKey)
 		}
 	}
-	return validateHpaAnnotations(service)
+	if err := validateServiceType(service); err != nil {
+		return err
+	}
+	if err := validateKeda(service); err != nil {
+		return err
+	}
+	if err := validateHpaAnnotations(service); err != nil {
+		return err
+	}
+	return validateVpaAnnotations(service)
 }
 
 // getServiceName returns the kubernetes resource name for a compose service:
@@ -1,9 +1,19 @@
 package kubepose_test
 
 import (
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strconv"
 	"strings"
 	"testing"
 
+	"filippo.io/age"
 	"github.com/compose-spec/compose-go/v2/types"
 	"github.com/middle-management/kubepose"
 	corev1 "k8s.io/api/core/v1"
@@ -132,9 +142,10 @@ func TestConvertNegative(t *testing.T) {
 		}
 	})
 
-	t.Run("invalid healthcheck port annotation is silently ignored", func(t *testing.T) {
+	t.Run("unknown healthcheck port name returns error", func(t *testing.T) {
 		t.Parallel()
-		// Documents existing behavior at convert.go getProbes.
+		// Non-numeric values name a container port, so a name no port
+		// declares is rejected instead of silently probing the first port.
 		project := projectWith(types.ServiceConfig{
 			Name:  "web",
 			Image: "nginx",
@@ -144,6 +155,23 @@ func TestConvertNegative(t *testing.T) {
 				kubepose.HealthcheckHttpGetPortAnnotationKey: "not-a-port",
 			},
 		})
+		_, err := kubepose.Transformer{}.Convert(project)
+		if err == nil || !strings.Contains(err.Error(), "nor the name of a declared port") {
+			t.Fatalf("expected unknown port name error, got: %v", err)
+		}
+	})
+
+	t.Run("healthcheck port annotation references a named port", func(t *testing.T) {
+		t.Parallel()
+		project := projectWith(types.ServiceConfig{
+			Name:  "web",
+			Image: "nginx",
+			Ports: []types.ServicePortConfig{{Target: 8080, Protocol: "tcp", Name: "http"}},
+			Annotations: map[string]string{
+				kubepose.HealthcheckHttpGetPathAnnotationKey: "/healthz",
+				kubepose.HealthcheckHttpGetPortAnnotationKey: "http",
+			},
+		})
 		resources, err := kubepose.Transformer{}.Convert(project)
 		if err != nil {
 			t.Fatalf("unexpected error: %v", err)
@@ -152,9 +180,55 @@ func TestConvertNegative(t *testing.T) {
 		if c.LivenessProbe == nil || c.LivenessProbe.HTTPGet == nil {
 			t.Fatal("expected an HTTP liveness probe")
 		}
-		// Falls back to first declared port instead of erroring.
-		if got := c.LivenessProbe.HTTPGet.Port.IntVal; got != 8080 {
-			t.Fatalf("expected fallback to first port 8080, got %d", got)
+		if got := c.LivenessProbe.HTTPGet.Port.String(); got != "http" {
+			t.Fatalf("expected probe on named port http, got %q", got)
+		}
+		if c.Ports[0].Name != "http" {
+			t.Fatalf("expected container port named http, got %+v", c.Ports[0])
+		}
+	})
+
+	t.Run("port range above the cap returns error", func(t *testing.T) {
+		t.Parallel()
+		project := projectWith(types.ServiceConfig{
+			Name:   "rtp",
+			Image:  "asterisk",
+			Expose: []string{"10000-20000/udp"},
+		})
+		_, err := kubepose.Transformer{}.Convert(project)
+		if err == nil || !strings.Contains(err.Error(), "more than the limit") {
+			t.Fatalf("expected port range cap error, got: %v", err)
+		}
+	})
+
+	t.Run("port range expanded by compose above the cap returns error", func(t *testing.T) {
+		t.Parallel()
+		// compose-go expands "8000-8300:8000-8300" into single ports.
+		var ports []types.ServicePortConfig
+		for p := 8000; p <= 8300; p++ {
+			ports = append(ports, types.ServicePortConfig{Target: uint32(p), Published: strconv.Itoa(p), Protocol: "tcp", Mode: "ingress"})
+		}
+		project := projectWith(types.ServiceConfig{Name: "media", Image: "nginx", Ports: ports})
+		_, err := kubepose.Transformer{}.Convert(project)
+		if err == nil || !strings.Contains(err.Error(), "ports 8000-8300 are 301 consecutive ports, more than the limit of 100") {
+			t.Fatalf("expected port range cap error, got: %v", err)
+		}
+	})
+
+	t.Run("expose port annotation must match a TCP port", func(t *testing.T) {
+		t.Parallel()
+		project := projectWith(types.ServiceConfig{
+			Name:  "web",
+			Image: "nginx",
+			Ports: []types.ServicePortConfig{{Target: 80, Name: "http"}},
+			Annotations: map[string]string{
+				kubepose.ServiceExposeAnnotationKey:     "true",
+				kubepose.ServiceExposePortAnnotationKey: "admin",
+			},
+		})
+		_, err := kubepose.Transformer{}.Convert(project)
+		if err == nil || !strings.Contains(err.Error(), "does not match") {
+			t.Fatalf("expected unmatched expose port error, got: %v", err)
 		}
 	})
 
@@ -397,126 +471,1312 @@ func TestConvertNegative(t *testing.T) {
 			t.Fatalf("expected empty-schedule error, got: %v", err)
 		}
 	})
-}
 
-func projectWith(svc types.ServiceConfig) *types.Project {
-	return &types.Project{
-		Services: types.Services{svc.Name: svc},
-	}
-}
+	t.Run("invalid cronjob schedule returns error", func(t *testing.T) {
+		t.Parallel()
+		for _, schedule := range []string{"0 * * *", "61 * * * *", "0 0 * * FOO", "TZ=UTC 0 * * * *", "@fortnightly"} {
+			project := projectWith(types.ServiceConfig{
+				Name:        "job",
+				Image:       "alpine",
+				Annotations: map[string]string{kubepose.CronJobScheduleAnnotationKey: schedule},
+			})
+			_, err := kubepose.Transformer{}.Convert(project)
+			if err == nil || !strings.Contains(err.Error(), kubepose.CronJobScheduleAnnotationKey) {
+				t.Fatalf("schedule %q: expected schedule error, got: %v", schedule, err)
+			}
+		}
+	})
 
-func TestConvertHpaValidation(t *testing.T) {
-	t.Parallel()
+	t.Run("unknown cronjob time zone returns error", func(t *testing.T) {
+		t.Parallel()
+		project := projectWith(types.ServiceConfig{
+			Name:  "job",
+			Image: "alpine",
+			Annotations: map[string]string{
+				kubepose.CronJobScheduleAnnotationKey: "@hourly",
+				kubepose.CronJobTimeZoneAnnotationKey: "Mars/Olympus",
+			},
+		})
+		_, err := kubepose.Transformer{}.Convert(project)
+		if err == nil || !strings.Contains(err.Error(), "IANA time zone") {
+			t.Fatalf("expected time zone error, got: %v", err)
+		}
+	})
 
-	withCpuReservation := func(svc types.ServiceConfig) types.ServiceConfig {
-		if svc.Deploy == nil {
-			svc.Deploy = &types.DeployConfig{}
+	t.Run("cronjob settings without a schedule return error", func(t *testing.T) {
+		t.Parallel()
+		project := projectWith(types.ServiceConfig{
+			Name:        "web",
+			Image:       "nginx",
+			Annotations: map[string]string{kubepose.CronJobConcurrencyPolicyAnnotationKey: "Forbid"},
+		})
+		_, err := kubepose.Transformer{}.Convert(project)
+		if err == nil || !strings.Contains(err.Error(), "has no effect without") {
+			t.Fatalf("expected has no effect error, got: %v", err)
 		}
-		svc.Deploy.Resources.Reservations = &types.Resource{NanoCPUs: 2}
-		return svc
-	}
+	})
 
-	cases := []struct {
-		name    string
-		service types.ServiceConfig
-		wantErr string
-	}{
-		{
-			name: "minReplicas without maxReplicas names only the set key",
-			service: types.ServiceConfig{
-				Name: "web", Image: "nginx",
-				Annotations: map[string]string{kubepose.HpaMinReplicasAnnotationKey: "2"},
+	t.Run("gateway ingress API routes exposed services via the default Gateway", func(t *testing.T) {
+		t.Parallel()
+		project := projectWith(types.ServiceConfig{
+			Name:  "web",
+			Image: "nginx",
+			Ports: []types.ServicePortConfig{{Target: 80, Published: "8080"}},
+			Annotations: map[string]string{
+				kubepose.ServiceExposeAnnotationKey: "web.example.com",
 			},
-			wantErr: kubepose.HpaMinReplicasAnnotationKey + " has no effect without",
-		},
-		{
-			name: "cpu without maxReplicas names only the set key",
-			service: types.ServiceConfig{
-				Name: "web", Image: "nginx",
-				Annotations: map[string]string{kubepose.HpaCpuAnnotationKey: "70"},
+		})
+		transformer := kubepose.Transformer{IngressAPI: kubepose.IngressAPIGateway, Gateway: "infra/public"}
+		resources, err := transformer.Convert(project)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if len(resources.Ingresses) != 0 {
+			t.Fatalf("expected no ingresses, got %d", len(resources.Ingresses))
+		}
+		if len(resources.HTTPRoutes) != 1 {
+			t.Fatalf("expected 1 HTTPRoute, got %d", len(resources.HTTPRoutes))
+		}
+		parent := resources.HTTPRoutes[0].Spec.ParentRefs[0]
+		if parent.Name != "public" || parent.Namespace == nil || *parent.Namespace != "infra" {
+			t.Fatalf("expected parent infra/public, got %+v", parent)
+		}
+	})
+
+	t.Run("gateway ingress API without a parent Gateway returns error", func(t *testing.T) {
+		t.Parallel()
+		project := projectWith(types.ServiceConfig{
+			Name:  "web",
+			Image: "nginx",
+			Ports: []types.ServicePortConfig{{Target: 80}},
+			Annotations: map[string]string{
+				kubepose.ServiceExposeAnnotationKey: "true",
 			},
-			wantErr: kubepose.HpaCpuAnnotationKey + " has no effect without",
-		},
-		{
-			name: "non-numeric maxReplicas",
-			service: withCpuReservation(types.ServiceConfig{
-				Name: "web", Image: "nginx",
-				Annotations: map[string]string{kubepose.HpaMaxReplicasAnnotationKey: "lots"},
-			}),
-			wantErr: "must be a positive integer",
-		},
-		{
-			name: "maxReplicas overflowing int32 is rejected, not truncated",
-			service: withCpuReservation(types.ServiceConfig{
-				Name: "web", Image: "nginx",
-				// int32-truncates to 3; must fail validation instead of
-				// emitting a plausible-looking wrong HPA.
-				Annotations: map[string]string{kubepose.HpaMaxReplicasAnnotationKey: "4294967299"},
-			}),
-			wantErr: "must be a positive integer",
-		},
-		{
-			name: "minReplicas above maxReplicas",
-			service: withCpuReservation(types.ServiceConfig{
-				Name: "web", Image: "nginx",
-				Annotations: map[string]string{
-					kubepose.HpaMinReplicasAnnotationKey: "5",
-					kubepose.HpaMaxReplicasAnnotationKey: "3",
+		})
+		_, err := kubepose.Transformer{IngressAPI: kubepose.IngressAPIGateway}.Convert(project)
+		if err == nil || !strings.Contains(err.Error(), "requires a parent Gateway") {
+			t.Fatalf("expected missing parent Gateway error, got: %v", err)
+		}
+	})
+
+	t.Run("malformed gateway annotation returns error", func(t *testing.T) {
+		t.Parallel()
+		project := projectWith(types.ServiceConfig{
+			Name:  "web",
+			Image: "nginx",
+			Ports: []types.ServicePortConfig{{Target: 80}},
+			Annotations: map[string]string{
+				kubepose.ServiceExposeAnnotationKey:        "true",
+				kubepose.ServiceExposeGatewayAnnotationKey: "a/b/c",
+			},
+		})
+		_, err := kubepose.Transformer{}.Convert(project)
+		if err == nil || !strings.Contains(err.Error(), "invalid parent Gateway") {
+			t.Fatalf("expected invalid parent Gateway error, got: %v", err)
+		}
+	})
+
+	t.Run("unknown ingress API returns error", func(t *testing.T) {
+		t.Parallel()
+		project := projectWith(types.ServiceConfig{Name: "web", Image: "nginx"})
+		_, err := kubepose.Transformer{IngressAPI: "istio"}.Convert(project)
+		if err == nil || !strings.Contains(err.Error(), "unsupported ingress API") {
+			t.Fatalf("expected unsupported ingress API error, got: %v", err)
+		}
+	})
+
+	t.Run("hostname nameserver returns error", func(t *testing.T) {
+		t.Parallel()
+		project := projectWith(types.ServiceConfig{
+			Name:  "web",
+			Image: "nginx",
+			DNS:   types.StringList{"dns.example.com"},
+		})
+		_, err := kubepose.Transformer{}.Convert(project)
+		if err == nil || !strings.Contains(err.Error(), "nameserver IP addresses") {
+			t.Fatalf("expected nameserver IP error, got: %v", err)
+		}
+	})
+
+	t.Run("dotted domainname returns error", func(t *testing.T) {
+		t.Parallel()
+		project := projectWith(types.ServiceConfig{
+			Name:       "web",
+			Image:      "nginx",
+			DomainName: "example.com",
+		})
+		_, err := kubepose.Transformer{}.Convert(project)
+		if err == nil || !strings.Contains(err.Error(), "single DNS label") {
+			t.Fatalf("expected single DNS label error, got: %v", err)
+		}
+	})
+
+	t.Run("group members with conflicting DNS return error", func(t *testing.T) {
+		t.Parallel()
+		project := &types.Project{
+			Services: types.Services{
+				"a": types.ServiceConfig{
+					Name: "a", Image: "nginx",
+					DNSSearch:   types.StringList{"a.example.com"},
+					Annotations: map[string]string{kubepose.ServiceGroupAnnotationKey: "app"},
 				},
-			}),
-			wantErr: "must not exceed",
-		},
-		{
-			name: "deploy.replicas above maxReplicas as implicit floor",
-			service: withCpuReservation(types.ServiceConfig{
-				Name: "web", Image: "nginx",
-				Deploy:      &types.DeployConfig{Replicas: intPtr(8)},
-				Annotations: map[string]string{kubepose.HpaMaxReplicasAnnotationKey: "3"},
-			}),
-			wantErr: "must not exceed",
-		},
-		{
-			name: "zero cpu target",
-			service: withCpuReservation(types.ServiceConfig{
-				Name: "web", Image: "nginx",
-				Annotations: map[string]string{
-					kubepose.HpaMaxReplicasAnnotationKey: "3",
-					kubepose.HpaCpuAnnotationKey:         "0",
+				"b": types.ServiceConfig{
+					Name: "b", Image: "nginx",
+					DNSSearch:   types.StringList{"b.example.com"},
+					Annotations: map[string]string{kubepose.ServiceGroupAnnotationKey: "app"},
 				},
-			}),
-			wantErr: "positive integer percentage",
-		},
-		{
-			name: "combined with cronjob schedule",
-			service: withCpuReservation(types.ServiceConfig{
-				Name: "web", Image: "nginx",
-				Annotations: map[string]string{
-					kubepose.HpaMaxReplicasAnnotationKey:  "3",
-					kubepose.CronJobScheduleAnnotationKey: "0 * * * *",
+			},
+		}
+		_, err := kubepose.Transformer{}.Convert(project)
+		if err == nil || !strings.Contains(err.Error(), "conflict with another service") {
+			t.Fatalf("expected DNS conflict error, got: %v", err)
+		}
+	})
+
+	t.Run("network_mode none returns error", func(t *testing.T) {
+		t.Parallel()
+		project := projectWith(types.ServiceConfig{Name: "web", Image: "nginx", NetworkMode: "none"})
+		_, err := kubepose.Transformer{}.Convert(project)
+		if err == nil || !strings.Contains(err.Error(), "network_mode") {
+			t.Fatalf("expected network_mode error, got: %v", err)
+		}
+	})
+
+	t.Run("sharing a namespace with a standalone pod returns error", func(t *testing.T) {
+		t.Parallel()
+		project := &types.Project{
+			Services: types.Services{
+				"migrate": types.ServiceConfig{Name: "migrate", Image: "busybox", Restart: "no"},
+				"web":     types.ServiceConfig{Name: "web", Image: "nginx", NetworkMode: "service:migrate"},
+			},
+		}
+		_, err := kubepose.Transformer{}.Convert(project)
+		if err == nil || !strings.Contains(err.Error(), "does not run in a shared pod") {
+			t.Fatalf("expected shared pod error, got: %v", err)
+		}
+	})
+
+	t.Run("sharing a namespace across explicit groups returns error", func(t *testing.T) {
+		t.Parallel()
+		project := &types.Project{
+			Services: types.Services{
+				"a": types.ServiceConfig{
+					Name: "a", Image: "nginx",
+					Annotations: map[string]string{kubepose.ServiceGroupAnnotationKey: "one"},
 				},
-			}),
-			wantErr: "cannot be combined with",
-		},
-		{
-			name: "combined with global mode",
-			service: types.ServiceConfig{
-				Name: "web", Image: "nginx",
-				Deploy: &types.DeployConfig{
-					Mode:      "global",
-					Resources: types.Resources{Reservations: &types.Resource{NanoCPUs: 1}},
+				"b": types.ServiceConfig{
+					Name: "b", Image: "nginx", Pid: "service:a",
+					Annotations: map[string]string{kubepose.ServiceGroupAnnotationKey: "two"},
 				},
-				Annotations: map[string]string{kubepose.HpaMaxReplicasAnnotationKey: "3"},
 			},
-			wantErr: "deploy.mode: global",
-		},
-		{
-			name: "missing cpu reservation",
-			service: types.ServiceConfig{
-				Name: "web", Image: "nginx",
-				Annotations: map[string]string{kubepose.HpaMaxReplicasAnnotationKey: "3"},
+		}
+		_, err := kubepose.Transformer{}.Convert(project)
+		if err == nil || !strings.Contains(err.Error(), "shares a namespace") {
+			t.Fatalf("expected group conflict error, got: %v", err)
+		}
+	})
+
+	t.Run("group members disagreeing on host network return error", func(t *testing.T) {
+		t.Parallel()
+		project := &types.Project{
+			Services: types.Services{
+				"a": types.ServiceConfig{
+					Name: "a", Image: "nginx", NetworkMode: "host",
+					Annotations: map[string]string{kubepose.ServiceGroupAnnotationKey: "app"},
+				},
+				"b": types.ServiceConfig{
+					Name: "b", Image: "nginx",
+					Annotations: map[string]string{kubepose.ServiceGroupAnnotationKey: "app"},
+				},
 			},
-			wantErr: "requires deploy.resources.reservations.cpus",
-		},
+		}
+		_, err := kubepose.Transformer{}.Convert(project)
+		if err == nil || !strings.Contains(err.Error(), "host namespace") {
+			t.Fatalf("expected host namespace conflict error, got: %v", err)
+		}
+	})
+
+	t.Run("pre_stop hook with user returns error", func(t *testing.T) {
+		t.Parallel()
+		project := projectWith(types.ServiceConfig{
+			Name:    "web",
+			Image:   "nginx",
+			PreStop: []types.ServiceHook{{Command: types.ShellCommand{"nginx", "-s", "quit"}, User: "0"}},
+		})
+		_, err := kubepose.Transformer{}.Convert(project)
+		if err == nil || !strings.Contains(err.Error(), "pre_stop: user is not supported") {
+			t.Fatalf("expected pre_stop user error, got: %v", err)
+		}
+	})
+
+	t.Run("preStop sleep extends the termination grace period", func(t *testing.T) {
+		t.Parallel()
+		project := projectWith(types.ServiceConfig{
+			Name:        "web",
+			Image:       "nginx",
+			Annotations: map[string]string{kubepose.LifecyclePreStopSleepAnnotationKey: "30s"},
+		})
+		resources, err := kubepose.Transformer{}.Convert(project)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		grace := resources.Deployments[0].Spec.Template.Spec.TerminationGracePeriodSeconds
+		if grace == nil || *grace != 60 {
+			t.Fatalf("expected terminationGracePeriodSeconds 60 (default 30 plus the sleep), got %v", grace)
+		}
+	})
+
+	t.Run("preStop sleep combined with pre_stop returns error", func(t *testing.T) {
+		t.Parallel()
+		project := projectWith(types.ServiceConfig{
+			Name:        "web",
+			Image:       "nginx",
+			PreStop:     []types.ServiceHook{{Command: types.ShellCommand{"nginx", "-s", "quit"}}},
+			Annotations: map[string]string{kubepose.LifecyclePreStopSleepAnnotationKey: "5"},
+		})
+		_, err := kubepose.Transformer{}.Convert(project)
+		if err == nil || !strings.Contains(err.Error(), "single preStop handler") {
+			t.Fatalf("expected single preStop handler error, got: %v", err)
+		}
+	})
+
+	t.Run("stop_signal becomes the native stopSignal on newer clusters", func(t *testing.T) {
+		t.Parallel()
+		project := projectWith(types.ServiceConfig{Name: "web", Image: "nginx", StopSignal: "QUIT"})
+		resources, err := kubepose.Transformer{KubeVersion: "v1.34.1"}.Convert(project)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		spec := resources.Deployments[0].Spec.Template.Spec
+		lifecycle := spec.Containers[0].Lifecycle
+		if lifecycle == nil || lifecycle.StopSignal == nil || *lifecycle.StopSignal != corev1.SIGQUIT || lifecycle.PreStop != nil {
+			t.Fatalf("expected stopSignal SIGQUIT without preStop, got %+v", lifecycle)
+		}
+		if spec.OS == nil || spec.OS.Name != corev1.Linux {
+			t.Fatalf("expected pod os linux, got %+v", spec.OS)
+		}
+	})
+
+	t.Run("stop_signal with pre_stop on older clusters returns error", func(t *testing.T) {
+		t.Parallel()
+		project := projectWith(types.ServiceConfig{
+			Name:       "web",
+			Image:      "nginx",
+			StopSignal: "SIGQUIT",
+			PreStop:    []types.ServiceHook{{Command: types.ShellCommand{"sleep", "5"}}},
+		})
+		_, err := kubepose.Transformer{KubeVersion: "1.30"}.Convert(project)
+		if err == nil || !strings.Contains(err.Error(), "cannot be combined with pre_stop") {
+			t.Fatalf("expected pre_stop combination error, got: %v", err)
+		}
+	})
+
+	t.Run("stop_signal in a shared process namespace on older clusters returns error", func(t *testing.T) {
+		t.Parallel()
+		project := &types.Project{
+			Services: types.Services{
+				"app":   types.ServiceConfig{Name: "app", Image: "php-fpm", StopSignal: "SIGQUIT"},
+				"debug": types.ServiceConfig{Name: "debug", Image: "busybox", Pid: "service:app"},
+			},
+		}
+		_, err := kubepose.Transformer{}.Convert(project)
+		if err == nil || !strings.Contains(err.Error(), `not the service's process with pid "service:app"`) {
+			t.Fatalf("expected shared process namespace error, got: %v", err)
+		}
+		if _, err := (kubepose.Transformer{KubeVersion: "1.34"}).Convert(project); err != nil {
+			t.Fatalf("expected the native stopSignal to be allowed, got: %v", err)
+		}
+	})
+
+	t.Run("invalid kube version returns error", func(t *testing.T) {
+		t.Parallel()
+		project := projectWith(types.ServiceConfig{Name: "web", Image: "nginx"})
+		_, err := kubepose.Transformer{KubeVersion: "latest"}.Convert(project)
+		if err == nil || !strings.Contains(err.Error(), "invalid Kubernetes version") {
+			t.Fatalf("expected invalid Kubernetes version error, got: %v", err)
+		}
+	})
+
+	t.Run("waiting for a standalone pod dependency returns error", func(t *testing.T) {
+		t.Parallel()
+		project := &types.Project{
+			Services: types.Services{
+				"migrate": types.ServiceConfig{Name: "migrate", Image: "busybox", Restart: "no"},
+				"web": types.ServiceConfig{
+					Name: "web", Image: "nginx",
+					DependsOn: types.DependsOnConfig{"migrate": {Condition: types.ServiceConditionStarted, Required: true}},
+				},
+			},
+		}
+		_, err := kubepose.Transformer{DependsOn: kubepose.DependsOnWait}.Convert(project)
+		if err == nil || !strings.Contains(err.Error(), "gets no Kubernetes Service") {
+			t.Fatalf("expected no Kubernetes Service error, got: %v", err)
+		}
+	})
+
+	t.Run("unknown dependsOn annotation returns error", func(t *testing.T) {
+		t.Parallel()
+		project := projectWith(types.ServiceConfig{
+			Name:        "web",
+			Image:       "nginx",
+			Annotations: map[string]string{kubepose.DependsOnAnnotationKey: "block"},
+		})
+		_, err := kubepose.Transformer{}.Convert(project)
+		if err == nil || !strings.Contains(err.Error(), kubepose.DependsOnAnnotationKey) {
+			t.Fatalf("expected dependsOn annotation error, got: %v", err)
+		}
+	})
+
+	t.Run("dependencies in the same pod are not waited for", func(t *testing.T) {
+		t.Parallel()
+		group := map[string]string{kubepose.ServiceGroupAnnotationKey: "app"}
+		project := &types.Project{
+			Services: types.Services{
+				"db": types.ServiceConfig{
+					Name: "db", Image: "postgres", Annotations: group,
+					Ports: []types.ServicePortConfig{{Target: 5432}},
+				},
+				"web": types.ServiceConfig{
+					Name: "web", Image: "nginx", Annotations: group,
+					DependsOn: types.DependsOnConfig{"db": {Condition: types.ServiceConditionHealthy, Required: true}},
+				},
+			},
+		}
+		resources, err := kubepose.Transformer{DependsOn: kubepose.DependsOnWait}.Convert(project)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if initContainers := resources.Deployments[0].Spec.Template.Spec.InitContainers; len(initContainers) != 0 {
+			t.Fatalf("expected no wait init containers, got %+v", initContainers)
+		}
+	})
+
+	t.Run("keda combined with an HPA returns error", func(t *testing.T) {
+		t.Parallel()
+		project := projectWith(types.ServiceConfig{
+			Name:        "worker",
+			Image:       "busybox",
+			Annotations: map[string]string{kubepose.HpaMaxReplicasAnnotationKey: "3"},
+			Extensions: types.Extensions{kubepose.ServiceExtensionKey: map[string]any{
+				"keda": map[string]any{"triggers": []any{map[string]any{"type": "cpu", "metadata": map[string]any{"value": "50"}}}},
+			}},
+		})
+		_, err := kubepose.Transformer{}.Convert(project)
+		if err == nil || !strings.Contains(err.Error(), "cannot be combined with "+kubepose.HpaMaxReplicasAnnotationKey) {
+			t.Fatalf("expected HPA combination error, got: %v", err)
+		}
+	})
+
+	t.Run("keda without triggers returns error", func(t *testing.T) {
+		t.Parallel()
+		project := projectWith(types.ServiceConfig{
+			Name:  "worker",
+			Image: "busybox",
+			Extensions: types.Extensions{kubepose.ServiceExtensionKey: map[string]any{
+				"keda": map[string]any{"maxReplicaCount": 5},
+			}},
+		})
+		_, err := kubepose.Transformer{}.Convert(project)
+		if err == nil || !strings.Contains(err.Error(), "at least one trigger") {
+			t.Fatalf("expected missing trigger error, got: %v", err)
+		}
+	})
+
+	t.Run("keda cooldownPeriod on a run-once service returns error", func(t *testing.T) {
+		t.Parallel()
+		project := projectWith(types.ServiceConfig{
+			Name:    "job",
+			Image:   "busybox",
+			Restart: "no",
+			Extensions: types.Extensions{kubepose.ServiceExtensionKey: map[string]any{
+				"keda": map[string]any{
+					"cooldownPeriod": 60,
+					"triggers":       []any{map[string]any{"type": "cron", "metadata": map[string]any{"timezone": "UTC"}}},
+				},
+			}},
+		})
+		_, err := kubepose.Transformer{}.Convert(project)
+		if err == nil || !strings.Contains(err.Error(), "ScaledJob") {
+			t.Fatalf("expected ScaledJob setting error, got: %v", err)
+		}
+	})
+
+	t.Run("vpa with an invalid update mode returns error", func(t *testing.T) {
+		t.Parallel()
+		project := projectWith(types.ServiceConfig{
+			Name:        "web",
+			Image:       "nginx",
+			Annotations: map[string]string{kubepose.VpaUpdateModeAnnotationKey: "Sometimes"},
+		})
+		_, err := kubepose.Transformer{}.Convert(project)
+		if err == nil || !strings.Contains(err.Error(), kubepose.VpaUpdateModeAnnotationKey) {
+			t.Fatalf("expected update mode error, got: %v", err)
+		}
+	})
+
+	t.Run("vpa combined with a CPU-based HPA returns error", func(t *testing.T) {
+		t.Parallel()
+		project := projectWith(types.ServiceConfig{
+			Name:  "web",
+			Image: "nginx",
+			Annotations: map[string]string{
+				kubepose.HpaMaxReplicasAnnotationKey: "5",
+				kubepose.VpaUpdateModeAnnotationKey:  "Auto",
+			},
+			Deploy: &types.DeployConfig{Resources: types.Resources{
+				Reservations: &types.Resource{NanoCPUs: 0.5},
+			}},
+		})
+		_, err := kubepose.Transformer{}.Convert(project)
+		if err == nil || !strings.Contains(err.Error(), "CPU-based HPA") {
+			t.Fatalf("expected HPA combination error, got: %v", err)
+		}
+	})
+
+	t.Run("vpa on a run-once service returns error", func(t *testing.T) {
+		t.Parallel()
+		project := projectWith(types.ServiceConfig{
+			Name:        "job",
+			Image:       "busybox",
+			Restart:     "no",
+			Annotations: map[string]string{kubepose.VpaUpdateModeAnnotationKey: "Initial"},
+		})
+		_, err := kubepose.Transformer{}.Convert(project)
+		if err == nil || !strings.Contains(err.Error(), "standalone Pod") {
+			t.Fatalf("expected standalone pod error, got: %v", err)
+		}
+	})
+
+	t.Run("vpa bounds every container of a group", func(t *testing.T) {
+		t.Parallel()
+		limits := &types.DeployConfig{Resources: types.Resources{
+			Limits: &types.Resource{NanoCPUs: 1, MemoryBytes: 256 * 1024 * 1024},
+		}}
+		project := &types.Project{
+			Services: types.Services{
+				"app": types.ServiceConfig{
+					Name: "app", Image: "app", Deploy: limits,
+					Annotations: map[string]string{
+						kubepose.ServiceGroupAnnotationKey:  "app",
+						kubepose.VpaUpdateModeAnnotationKey: "Auto",
+					},
+				},
+				"proxy": types.ServiceConfig{
+					Name: "proxy", Image: "envoy", Deploy: limits, Restart: "always",
+					Annotations: map[string]string{
+						kubepose.ServiceGroupAnnotationKey:  "app",
+						kubepose.ContainerTypeAnnotationKey: "init",
+					},
+				},
+				"worker": types.ServiceConfig{
+					Name: "worker", Image: "worker", Deploy: limits,
+					Annotations: map[string]string{kubepose.ServiceGroupAnnotationKey: "app"},
+				},
+			},
+		}
+		resources, err := kubepose.Transformer{}.Convert(project)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if len(resources.VerticalPodAutoscalers) != 1 {
+			t.Fatalf("expected one VPA, got %d", len(resources.VerticalPodAutoscalers))
+		}
+		var names []string
+		for _, policy := range resources.VerticalPodAutoscalers[0].Spec.ResourcePolicy.ContainerPolicies {
+			if policy.MaxAllowed.Cpu().String() != "1" {
+				t.Errorf("container %s: expected maxAllowed cpu 1, got %v", policy.ContainerName, policy.MaxAllowed)
+			}
+			names = append(names, policy.ContainerName)
+		}
+		if strings.Join(names, ",") != "proxy,app,worker" {
+			t.Fatalf("expected policies for proxy, app and worker, got %v", names)
+		}
+	})
+
+	t.Run("pids limit returns error", func(t *testing.T) {
+		t.Parallel()
+		project := projectWith(types.ServiceConfig{
+			Name:  "web",
+			Image: "nginx",
+			Deploy: &types.DeployConfig{Resources: types.Resources{
+				Limits: &types.Resource{Pids: 100},
+			}},
+		})
+		_, err := kubepose.Transformer{}.Convert(project)
+		if err == nil || !strings.Contains(err.Error(), "pids") {
+			t.Fatalf("expected pids error, got: %v", err)
+		}
+	})
+
+	t.Run("gpu reservation of all devices returns error", func(t *testing.T) {
+		t.Parallel()
+		project := projectWith(types.ServiceConfig{
+			Name:  "trainer",
+			Image: "busybox",
+			Deploy: &types.DeployConfig{Resources: types.Resources{
+				Reservations: &types.Resource{Devices: []types.DeviceRequest{
+					{Capabilities: []string{"gpu"}, Count: -1},
+				}},
+			}},
+		})
+		_, err := kubepose.Transformer{}.Convert(project)
+		if err == nil || !strings.Contains(err.Error(), "count all") {
+			t.Fatalf("expected count error, got: %v", err)
+		}
+	})
+
+	t.Run("gpu reservation by device_ids returns error", func(t *testing.T) {
+		t.Parallel()
+		project := projectWith(types.ServiceConfig{
+			Name:  "trainer",
+			Image: "busybox",
+			Deploy: &types.DeployConfig{Resources: types.Resources{
+				Reservations: &types.Resource{Devices: []types.DeviceRequest{
+					{Capabilities: []string{"gpu"}, IDs: []string{"0", "1"}},
+				}},
+			}},
+		})
+		_, err := kubepose.Transformer{}.Convert(project)
+		if err == nil || !strings.Contains(err.Error(), "device_ids") {
+			t.Fatalf("expected device_ids error, got: %v", err)
+		}
+	})
+
+	t.Run("invalid storage_opt size returns error", func(t *testing.T) {
+		t.Parallel()
+		project := projectWith(types.ServiceConfig{
+			Name:       "web",
+			Image:      "nginx",
+			StorageOpt: map[string]string{"size": "lots"},
+		})
+		_, err := kubepose.Transformer{}.Convert(project)
+		if err == nil || !strings.Contains(err.Error(), "storage_opt size") {
+			t.Fatalf("expected storage_opt error, got: %v", err)
+		}
+	})
+
+	t.Run("node-level sysctl returns error", func(t *testing.T) {
+		t.Parallel()
+		project := projectWith(types.ServiceConfig{
+			Name:    "web",
+			Image:   "nginx",
+			Sysctls: types.Mapping{"vm.max_map_count": "262144"},
+		})
+		_, err := kubepose.Transformer{}.Convert(project)
+		if err == nil || !strings.Contains(err.Error(), "only namespaced sysctls") {
+			t.Fatalf("expected node-level sysctl error, got: %v", err)
+		}
+	})
+
+	t.Run("network sysctl with host network returns error", func(t *testing.T) {
+		t.Parallel()
+		project := projectWith(types.ServiceConfig{
+			Name:        "web",
+			Image:       "nginx",
+			NetworkMode: "host",
+			Sysctls:     types.Mapping{"net.core.somaxconn": "1024"},
+		})
+		_, err := kubepose.Transformer{}.Convert(project)
+		if err == nil || !strings.Contains(err.Error(), "network_mode: host") {
+			t.Fatalf("expected host network error, got: %v", err)
+		}
+	})
+
+	t.Run("conflicting sysctls in a group return error", func(t *testing.T) {
+		t.Parallel()
+		group := map[string]string{kubepose.ServiceGroupAnnotationKey: "web"}
+		project := &types.Project{Services: types.Services{
+			"web": {
+				Name: "web", Image: "nginx", Restart: "always", Annotations: group,
+				Sysctls: types.Mapping{"net.core.somaxconn": "1024"},
+			},
+			"exporter": {
+				Name: "exporter", Image: "busybox", Restart: "always", Annotations: group,
+				Sysctls: types.Mapping{"net.core.somaxconn": "4096"},
+			},
+		}}
+		_, err := kubepose.Transformer{}.Convert(project)
+		if err == nil || !strings.Contains(err.Error(), "conflicts with") {
+			t.Fatalf("expected sysctl conflict error, got: %v", err)
+		}
+	})
+
+	t.Run("image pull secrets from the transformer come first", func(t *testing.T) {
+		t.Parallel()
+		project := projectWith(types.ServiceConfig{
+			Name:        "web",
+			Image:       "nginx",
+			Restart:     "always",
+			Annotations: map[string]string{kubepose.ImagePullSecretsAnnotationKey: "team-registry, global-registry"},
+		})
+		resources, err := kubepose.Transformer{ImagePullSecrets: []string{"global-registry"}}.Convert(project)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		want := []corev1.LocalObjectReference{{Name: "global-registry"}, {Name: "team-registry"}}
+		if got := resources.Deployments[0].Spec.Template.Spec.ImagePullSecrets; !reflect.DeepEqual(got, want) {
+			t.Fatalf("expected %v, got %v", want, got)
+		}
+	})
+
+	t.Run("invalid image pull secret name returns error", func(t *testing.T) {
+		t.Parallel()
+		project := projectWith(types.ServiceConfig{
+			Name:        "web",
+			Image:       "nginx",
+			Annotations: map[string]string{kubepose.ImagePullSecretsAnnotationKey: "Registry_Creds"},
+		})
+		_, err := kubepose.Transformer{}.Convert(project)
+		if err == nil || !strings.Contains(err.Error(), kubepose.ImagePullSecretsAnnotationKey) {
+			t.Fatalf("expected image pull secret error, got: %v", err)
+		}
+	})
+
+	t.Run("secret mode above 0777 returns error", func(t *testing.T) {
+		t.Parallel()
+		mode := types.FileMode(0o4755)
+		project := projectWith(types.ServiceConfig{
+			Name:    "web",
+			Image:   "nginx",
+			Secrets: []types.ServiceSecretConfig{{Source: "key", Mode: &mode}},
+		})
+		_, err := kubepose.Transformer{}.Convert(project)
+		if err == nil || !strings.Contains(err.Error(), "permission bits") {
+			t.Fatalf("expected mode error, got: %v", err)
+		}
+	})
+
+	t.Run("named config gid returns error", func(t *testing.T) {
+		t.Parallel()
+		project := projectWith(types.ServiceConfig{
+			Name:    "web",
+			Image:   "nginx",
+			Configs: []types.ServiceConfigObjConfig{{Source: "conf", GID: "nginx"}},
+		})
+		_, err := kubepose.Transformer{}.Convert(project)
+		if err == nil || !strings.Contains(err.Error(), "only numeric IDs") {
+			t.Fatalf("expected numeric gid error, got: %v", err)
+		}
+	})
+
+	t.Run("config file over 1MiB returns error", func(t *testing.T) {
+		t.Parallel()
+		path := filepath.Join(t.TempDir(), "GeoLite2-City.mmdb")
+		if err := os.WriteFile(path, make([]byte, 1<<20+1), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		project := projectWith(types.ServiceConfig{
+			Name:    "web",
+			Image:   "nginx",
+			Configs: []types.ServiceConfigObjConfig{{Source: "geoip"}},
+		})
+		project.Configs = types.Configs{"geoip": types.ConfigObjConfig{File: path}}
+		_, err := kubepose.Transformer{}.Convert(project)
+		if err == nil || !strings.Contains(err.Error(), "config geoip (file "+path+")") || !strings.Contains(err.Error(), "1MiB") {
+			t.Fatalf("expected size error naming the config and file, got: %v", err)
+		}
+	})
+
+	t.Run("bind-mounted file over 1MiB returns error", func(t *testing.T) {
+		t.Parallel()
+		path := filepath.Join(t.TempDir(), "keystore.jks")
+		if err := os.WriteFile(path, make([]byte, 1<<20+1), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		project := projectWith(types.ServiceConfig{
+			Name:    "web",
+			Image:   "nginx",
+			Restart: "always",
+			Volumes: []types.ServiceVolumeConfig{{Type: "bind", Source: path, Target: "/etc/keystore.jks"}},
+		})
+		_, err := kubepose.Transformer{}.Convert(project)
+		if err == nil || !strings.Contains(err.Error(), "bind mount of file "+path) {
+			t.Fatalf("expected size error naming the file, got: %v", err)
+		}
+	})
+
+	t.Run("externalSecret without remoteRef.key returns error", func(t *testing.T) {
+		t.Parallel()
+		project := projectWith(types.ServiceConfig{
+			Name:    "api",
+			Image:   "nginx",
+			Secrets: []types.ServiceSecretConfig{{Source: "token"}},
+		})
+		project.Secrets = types.Secrets{"token": types.SecretConfig{
+			External: true,
+			Name:     "token",
+			Extensions: types.Extensions{kubepose.ServiceExtensionKey: map[string]any{
+				"externalSecret": map[string]any{"secretStoreRef": map[string]any{"name": "vault"}},
+			}},
+		}}
+		_, err := kubepose.Transformer{}.Convert(project)
+		if err == nil || !strings.Contains(err.Error(), "requires remoteRef.key") {
+			t.Fatalf("expected remoteRef error, got: %v", err)
+		}
+	})
+
+	t.Run("external secrets are required with RequireExternalSecrets", func(t *testing.T) {
+		t.Parallel()
+		project := projectWith(types.ServiceConfig{
+			Name:    "api",
+			Image:   "nginx",
+			Restart: "always",
+			Secrets: []types.ServiceSecretConfig{{Source: "token"}},
+		})
+		project.Secrets = types.Secrets{"token": types.SecretConfig{External: true, Name: "token"}}
+		for _, require := range []bool{false, true} {
+			resources, err := kubepose.Transformer{RequireExternalSecrets: require}.Convert(project)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			optional := resources.Deployments[0].Spec.Template.Spec.Volumes[0].Secret.Optional
+			if require != (optional == nil) {
+				t.Fatalf("RequireExternalSecrets=%v: unexpected optional %v", require, optional)
+			}
+		}
+	})
+}
+
+// digestResolver is a kubepose.DigestResolver stand-in backed by a map.
+type digestResolver map[string]string
+
+func (r digestResolver) ResolveDigest(image string) (string, error) {
+	digest, ok := r[image]
+	if !ok {
+		return "", fmt.Errorf("image %q: tag not found", image)
+	}
+	return digest, nil
+}
+
+func TestConvertPinDigests(t *testing.T) {
+	t.Parallel()
+
+	const digest = "sha256:0123456789abcdef0123456789abcdef0123456789abcdef0123456789abcdef"
+	resolver := digestResolver{
+		"registry.local/app:v1":   digest,
+		"busybox":                 digest,
+		"registry.local/data:1.0": digest,
+	}
+
+	t.Run("service, hook and volume images are pinned", func(t *testing.T) {
+		t.Parallel()
+		project := projectWith(types.ServiceConfig{
+			Name: "app", Image: "registry.local/app:v1", Restart: "always",
+			PreStart: []types.ServiceHook{{Command: types.ShellCommand{"migrate"}, Image: "busybox"}},
+			Volumes:  []types.ServiceVolumeConfig{{Type: "image", Source: "registry.local/data:1.0", Target: "/data"}},
+		})
+		resources, err := kubepose.Transformer{DigestResolver: resolver}.Convert(project)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		spec := resources.Deployments[0].Spec.Template.Spec
+		if got := spec.Containers[0].Image; got != "registry.local/app:v1@"+digest {
+			t.Fatalf("expected pinned service image, got %q", got)
+		}
+		if got := spec.InitContainers[0].Image; got != "busybox@"+digest {
+			t.Fatalf("expected pinned hook image, got %q", got)
+		}
+		if got := spec.Volumes[0].Image.Reference; got != "registry.local/data:1.0@"+digest {
+			t.Fatalf("expected pinned volume image, got %q", got)
+		}
+	})
+
+	t.Run("images with a digest are kept", func(t *testing.T) {
+		t.Parallel()
+		image := "registry.local/app@" + digest
+		project := projectWith(types.ServiceConfig{Name: "app", Image: image, Restart: "always"})
+		resources, err := kubepose.Transformer{DigestResolver: digestResolver{}}.Convert(project)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if got := resources.Deployments[0].Spec.Template.Spec.Containers[0].Image; got != image {
+			t.Fatalf("expected %q, got %q", image, got)
+		}
+	})
+
+	t.Run("unresolvable tag returns error", func(t *testing.T) {
+		t.Parallel()
+		project := projectWith(types.ServiceConfig{Name: "app", Image: "registry.local/app:v2", Restart: "always"})
+		_, err := kubepose.Transformer{DigestResolver: resolver}.Convert(project)
+		if err == nil || !strings.Contains(err.Error(), `container "app": image "registry.local/app:v2"`) {
+			t.Fatalf("expected resolution error, got: %v", err)
+		}
+	})
+}
+
+func TestConvertEncryptSecrets(t *testing.T) {
+	t.Parallel()
+
+	file := filepath.Join(t.TempDir(), "db-password.txt")
+	if err := os.WriteFile(file, []byte("hunter2"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	project := projectWith(types.ServiceConfig{
+		Name: "app", Image: "app", Restart: "always",
+		Secrets: []types.ServiceSecretConfig{{Source: "db-password"}},
+	})
+	project.Name = "myapp"
+	project.Secrets = types.Secrets{"db-password": types.SecretConfig{File: file}}
+
+	plain, err := kubepose.Transformer{}.Convert(project)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	name := plain.Secrets[0].Name
+
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Run("sealed secrets keep their hashed name", func(t *testing.T) {
+		t.Parallel()
+		resources, err := kubepose.Transformer{SealPublicKey: &key.PublicKey, SealNamespace: "prod"}.Convert(project)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if len(resources.Secrets) != 0 {
+			t.Fatalf("expected no plaintext Secrets, got %d", len(resources.Secrets))
+		}
+		if len(resources.SealedSecrets) != 1 || resources.SealedSecrets[0].Name != name {
+			t.Fatalf("expected one SealedSecret named %s, got %+v", name, resources.SealedSecrets)
+		}
+		if got := resources.Deployments[0].Spec.Template.Spec.Volumes[0].Secret.SecretName; got != name {
+			t.Fatalf("expected the volume to mount %s, got %s", name, got)
+		}
+	})
+
+	t.Run("sops secrets keep their hashed name", func(t *testing.T) {
+		t.Parallel()
+		identity, err := age.GenerateX25519Identity()
+		if err != nil {
+			t.Fatal(err)
+		}
+		resources, err := kubepose.Transformer{SopsAgeRecipients: []string{identity.Recipient().String()}}.Convert(project)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if len(resources.Secrets) != 0 || len(resources.EncryptedSecrets) != 1 || resources.EncryptedSecrets[0].Name != name {
+			t.Fatalf("expected one encrypted Secret named %s, got %d plaintext and %d encrypted", name, len(resources.Secrets), len(resources.EncryptedSecrets))
+		}
+		var out strings.Builder
+		if err := resources.Write(&out); err != nil {
+			t.Fatal(err)
+		}
+		encoded := base64.StdEncoding.EncodeToString([]byte("hunter2"))
+		if strings.Contains(out.String(), encoded) || !strings.Contains(out.String(), "ENC[AES256_GCM,") {
+			t.Fatalf("expected encrypted secret data, got:\n%s", out.String())
+		}
+	})
+
+	t.Run("sealing requires a namespace or cluster-wide scope", func(t *testing.T) {
+		t.Parallel()
+		_, err := kubepose.Transformer{SealPublicKey: &key.PublicKey}.Convert(project)
+		if err == nil || !strings.Contains(err.Error(), "no namespace to seal it for") {
+			t.Fatalf("expected missing namespace error, got: %v", err)
+		}
+		resources, err := kubepose.Transformer{SealPublicKey: &key.PublicKey, SealClusterWide: true}.Convert(project)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if len(resources.SealedSecrets) != 1 {
+			t.Fatalf("expected one SealedSecret, got %d", len(resources.SealedSecrets))
+		}
+	})
+
+	t.Run("seal namespace must match the project namespace", func(t *testing.T) {
+		t.Parallel()
+		namespaced := projectWith(types.ServiceConfig{Name: "app", Image: "app", Restart: "always"})
+		namespaced.Extensions = types.Extensions{kubepose.ServiceExtensionKey: map[string]any{"namespace": "prod"}}
+		_, err := kubepose.Transformer{SealPublicKey: &key.PublicKey, SealNamespace: "staging"}.Convert(namespaced)
+		if err == nil || !strings.Contains(err.Error(), `seal namespace "staging" differs from x-kubepose.namespace "prod"`) {
+			t.Fatalf("expected namespace mismatch error, got: %v", err)
+		}
+		_, err = kubepose.Transformer{SealPublicKey: &key.PublicKey, SealNamespace: "prod"}.Convert(namespaced)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	})
+
+	t.Run("sealing and sops are exclusive", func(t *testing.T) {
+		t.Parallel()
+		_, err := kubepose.Transformer{SealPublicKey: &key.PublicKey, SopsAgeRecipients: []string{"age1"}}.Convert(project)
+		if err == nil || !strings.Contains(err.Error(), "not both") {
+			t.Fatalf("expected exclusivity error, got: %v", err)
+		}
+	})
+}
+
+func TestConvertSopsSecrets(t *testing.T) {
+	plain := filepath.Join(t.TempDir(), "db.yaml")
+	if err := os.WriteFile(plain, []byte("password: hunter2\nuser: app\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	convert := func(secret types.SecretConfig) (*kubepose.Resources, error) {
+		project := projectWith(types.ServiceConfig{
+			Name: "app", Image: "app", Restart: "always",
+			Secrets: []types.ServiceSecretConfig{{Source: "db"}},
+		})
+		project.Secrets = types.Secrets{"db": secret}
+		return kubepose.Transformer{}.Convert(project)
+	}
+
+	t.Run("hash is computed over the plaintext", func(t *testing.T) {
+		t.Setenv("SOPS_AGE_KEY_FILE", "testdata/sops-secrets/age.key")
+		encrypted, err := convert(types.SecretConfig{File: "testdata/sops-secrets/secrets/db.enc.yaml"})
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		decrypted, err := convert(types.SecretConfig{File: plain})
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if encrypted.Secrets[0].Name != decrypted.Secrets[0].Name {
+			t.Fatalf("expected the same name as the plaintext, got %s and %s", encrypted.Secrets[0].Name, decrypted.Secrets[0].Name)
+		}
+		if got := string(encrypted.Secrets[0].Data["db"]); got != "password: hunter2\nuser: app\n" {
+			t.Fatalf("expected decrypted content, got %q", got)
+		}
+	})
+
+	t.Run("missing age key file returns error", func(t *testing.T) {
+		t.Setenv("SOPS_AGE_KEY_FILE", "")
+		_, err := convert(types.SecretConfig{File: "testdata/sops-secrets/secrets/db.enc.yaml"})
+		if err == nil || !strings.Contains(err.Error(), "is SOPS-encrypted: SOPS_AGE_KEY_FILE is not set") {
+			t.Fatalf("expected age key error, got: %v", err)
+		}
+	})
+
+	t.Run("decrypt label requires SOPS metadata", func(t *testing.T) {
+		_, err := convert(types.SecretConfig{File: plain, Labels: types.Labels{kubepose.SecretDecryptLabelKey: "sops"}})
+		if err == nil || !strings.Contains(err.Error(), "has no SOPS metadata") {
+			t.Fatalf("expected missing metadata error, got: %v", err)
+		}
+	})
+
+	t.Run("unsupported decrypt label returns error", func(t *testing.T) {
+		_, err := convert(types.SecretConfig{File: plain, Labels: types.Labels{kubepose.SecretDecryptLabelKey: "vault"}})
+		if err == nil || !strings.Contains(err.Error(), `unsupported kubepose.secret.decrypt "vault"`) {
+			t.Fatalf("expected unsupported mode error, got: %v", err)
+		}
+	})
+}
+
+func TestConvertProjectExtension(t *testing.T) {
+	t.Parallel()
+
+	withExtension := func(ext map[string]any) *types.Project {
+		project := projectWith(types.ServiceConfig{Name: "app", Image: "app", Restart: "always"})
+		project.Extensions = types.Extensions{"x-kubepose": ext}
+		return project
+	}
+
+	for _, tt := range []struct {
+		name string
+		ext  map[string]any
+		want string
+	}{
+		{"unknown field", map[string]any{"namespaces": "prod"}, `unknown field "namespaces"`},
+		{"invalid namespace", map[string]any{"namespace": "Prod"}, `x-kubepose.namespace "Prod"`},
+		{"invalid common label", map[string]any{"commonLabels": map[string]any{"team": "a b"}}, `x-kubepose.commonLabels "team"`},
+		{"unknown annotation default", map[string]any{"defaults": map[string]any{"annotations": map[string]any{"kubepose.hpa.max": "3"}}}, `x-kubepose.defaults.annotations: unknown key "kubepose.hpa.max"`},
+		{"host path volume default", map[string]any{"defaults": map[string]any{"volumeLabels": map[string]any{"kubepose.volume.hostPath": "/data"}}}, `x-kubepose.defaults.volumeLabels: unknown key "kubepose.volume.hostPath"`},
+		{"unsupported output", map[string]any{"output": "toml"}, `x-kubepose.output "toml"`},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			_, err := kubepose.Transformer{}.Convert(withExtension(tt.ext))
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Fatalf("expected error containing %q, got: %v", tt.want, err)
+			}
+		})
+	}
+
+	t.Run("json output", func(t *testing.T) {
+		t.Parallel()
+		resources, err := kubepose.Transformer{}.Convert(withExtension(map[string]any{"output": "json", "namespace": "prod"}))
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		var out strings.Builder
+		if err := resources.Write(&out); err != nil {
+			t.Fatal(err)
+		}
+		var list struct {
+			Kind  string `json:"kind"`
+			Items []struct {
+				Kind     string `json:"kind"`
+				Metadata struct {
+					Namespace string `json:"namespace"`
+				} `json:"metadata"`
+			} `json:"items"`
+		}
+		if err := json.Unmarshal([]byte(out.String()), &list); err != nil {
+			t.Fatalf("expected JSON output: %v\n%s", err, out.String())
+		}
+		if list.Kind != "List" || len(list.Items) == 0 || list.Items[0].Metadata.Namespace != "prod" {
+			t.Fatalf("expected a List of objects in namespace prod, got:\n%s", out.String())
+		}
+	})
+}
+
+func TestConvertServiceExtension(t *testing.T) {
+	t.Parallel()
+
+	withExtension := func(ext map[string]any, annotations map[string]string) *types.Project {
+		return projectWith(types.ServiceConfig{
+			Name:        "app",
+			Image:       "app",
+			Restart:     "always",
+			Annotations: annotations,
+			Extensions:  types.Extensions{"x-kubepose": ext},
+		})
+	}
+
+	for _, tt := range []struct {
+		name        string
+		ext         map[string]any
+		annotations map[string]string
+		want        string
+	}{
+		{"unknown field", map[string]any{"expose": map[string]any{"host": "a.example.com"}}, nil, "unknown field x-kubepose.expose.host"},
+		{"wrong type", map[string]any{"cronjob": map[string]any{"schedule": "@daily", "backoffLimit": "x"}}, nil, "backoffLimit"},
+		{"field and annotation", map[string]any{"serviceAccountName": "web"}, map[string]string{"kubepose.service.serviceAccountName": "api"}, "x-kubepose.serviceAccountName and the kubepose.service.serviceAccountName annotation cannot both be set"},
+		{"healthcheck port list", map[string]any{"healthcheck": map[string]any{"httpGet": map[string]any{"path": "/", "port": []any{8080}}}}, nil, "x-kubepose.healthcheck.httpGet.port"},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			_, err := kubepose.Transformer{}.Convert(withExtension(tt.ext, tt.annotations))
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Fatalf("expected error containing %q, got: %v", tt.want, err)
+			}
+		})
+	}
+
+	t.Run("healthcheck port takes a port name", func(t *testing.T) {
+		t.Parallel()
+		project := withExtension(map[string]any{
+			"healthcheck": map[string]any{"httpGet": map[string]any{"path": "/healthz", "port": "http"}},
+		}, nil)
+		app := project.Services["app"]
+		app.Ports = []types.ServicePortConfig{{Target: 8080, Protocol: "tcp", Name: "http"}}
+		project.Services["app"] = app
+		resources, err := kubepose.Transformer{}.Convert(project)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		probe := resources.Deployments[0].Spec.Template.Spec.Containers[0].LivenessProbe
+		if probe == nil || probe.HTTPGet == nil || probe.HTTPGet.Port.String() != "http" {
+			t.Fatalf("expected an HTTP probe on named port http, got %+v", probe)
+		}
+	})
+
+	t.Run("annotations are aliases", func(t *testing.T) {
+		t.Parallel()
+		write := func(project *types.Project) string {
+			resources, err := kubepose.Transformer{}.Convert(project)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			var out strings.Builder
+			if err := resources.Write(&out); err != nil {
+				t.Fatal(err)
+			}
+			return out.String()
+		}
+		typed := write(withExtension(map[string]any{
+			"serviceAccountName": "web",
+			"cronjob":            map[string]any{"schedule": "@daily", "backoffLimit": 2},
+		}, nil))
+		annotated := write(withExtension(nil, map[string]string{
+			"kubepose.service.serviceAccountName": "web",
+			"kubepose.cronjob.schedule":           "@daily",
+			"kubepose.cronjob.backoffLimit":       "2",
+		}))
+		if typed != annotated {
+			t.Fatalf("expected identical output, got:\n%s\nand:\n%s", typed, annotated)
+		}
+		if strings.Contains(typed, "kubepose.") {
+			t.Fatalf("expected control annotations to be stripped, got:\n%s", typed)
+		}
+	})
+}
+
+func TestConvertControlAnnotations(t *testing.T) {
+	t.Parallel()
+
+	convert := func(t *testing.T, transformer kubepose.Transformer) string {
+		project := projectWith(types.ServiceConfig{
+			Name:    "app",
+			Image:   "app",
+			Restart: "always",
+			Annotations: map[string]string{
+				"kubepose.hpa.maxReplicas": "3",
+				"example.com/owner":        "team-a",
+			},
+			Deploy:  &types.DeployConfig{Resources: types.Resources{Reservations: &types.Resource{NanoCPUs: 1}}},
+			Secrets: []types.ServiceSecretConfig{{Source: "token"}},
+		})
+		project.Secrets = types.Secrets{"token": types.SecretConfig{
+			Content: "hunter2",
+			Labels:  types.Labels{kubepose.SecretSubPathLabelKey: "token"},
+		}}
+		resources, err := transformer.Convert(project)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		var out strings.Builder
+		if err := resources.Write(&out); err != nil {
+			t.Fatal(err)
+		}
+		return out.String()
+	}
+	annotations := map[string]string{kubepose.VersionAnnotationKey: "v1.2.3"}
+
+	t.Run("stripped by default", func(t *testing.T) {
+		t.Parallel()
+		out := convert(t, kubepose.Transformer{Annotations: annotations})
+		for _, want := range []string{"kubepose.version: v1.2.3", "kubepose.secret.hmacKey:", "kubepose.secret: token", "example.com/owner: team-a"} {
+			if !strings.Contains(out, want) {
+				t.Errorf("expected %q to be kept, got:\n%s", want, out)
+			}
+		}
+		for _, unwanted := range []string{"kubepose.hpa.maxReplicas", "kubepose.secret.subPath"} {
+			if strings.Contains(out, unwanted) {
+				t.Errorf("expected %q to be stripped, got:\n%s", unwanted, out)
+			}
+		}
+	})
+
+	t.Run("kept on request", func(t *testing.T) {
+		t.Parallel()
+		out := convert(t, kubepose.Transformer{Annotations: annotations, KeepControlAnnotations: true})
+		for _, want := range []string{"kubepose.hpa.maxReplicas: \"3\"", "kubepose.secret.subPath: token"} {
+			if !strings.Contains(out, want) {
+				t.Errorf("expected %q to be kept, got:\n%s", want, out)
+			}
+		}
+	})
+}
+
+func projectWith(svc types.ServiceConfig) *types.Project {
+	return &types.Project{
+		Services: types.Services{svc.Name: svc},
+	}
+}
+
+func TestConvertHpaValidation(t *testing.T) {
+	t.Parallel()
+
+	withCpuReservation := func(svc types.ServiceConfig) types.ServiceConfig {
+		if svc.Deploy == nil {
+			svc.Deploy = &types.DeployConfig{}
+		}
+		svc.Deploy.Resources.Reservations = &types.Resource{NanoCPUs: 2}
+		return svc
+	}
+
+	cases := []struct {
+		name    string
+		service types.ServiceConfig
+		wantErr string
+	}{
+		{
+			name: "minReplicas without maxReplicas names only the set key",
+			service: types.ServiceConfig{
+				Name: "web", Image: "nginx",
+				Annotations: map[string]string{kubepose.HpaMinReplicasAnnotationKey: "2"},
+			},
+			wantErr: kubepose.HpaMinReplicasAnnotationKey + " has no effect without",
+		},
+		{
+			name: "cpu without maxReplicas names only the set key",
+			service: types.ServiceConfig{
+				Name: "web", Image: "nginx",
+				Annotations: map[string]string{kubepose.HpaCpuAnnotationKey: "70"},
+			},
+			wantErr: kubepose.HpaCpuAnnotationKey + " has no effect without",
+		},
+		{
+			name: "non-numeric maxReplicas",
+			service: withCpuReservation(types.ServiceConfig{
+				Name: "web", Image: "nginx",
+				Annotations: map[string]string{kubepose.HpaMaxReplicasAnnotationKey: "lots"},
+			}),
+			wantErr: "must be a positive integer",
+		},
+		{
+			name: "maxReplicas overflowing int32 is rejected, not truncated",
+			service: withCpuReservation(types.ServiceConfig{
+				Name: "web", Image: "nginx",
+				// int32-truncates to 3; must fail validation instead of
+				// emitting a plausible-looking wrong HPA.
+				Annotations: map[string]string{kubepose.HpaMaxReplicasAnnotationKey: "4294967299"},
+			}),
+			wantErr: "must be a positive integer",
+		},
+		{
+			name: "minReplicas above maxReplicas",
+			service: withCpuReservation(types.ServiceConfig{
+				Name: "web", Image: "nginx",
+				Annotations: map[string]string{
+					kubepose.HpaMinReplicasAnnotationKey: "5",
+					kubepose.HpaMaxReplicasAnnotationKey: "3",
+				},
+			}),
+			wantErr: "must not exceed",
+		},
+		{
+			name: "deploy.replicas above maxReplicas as implicit floor",
+			service: withCpuReservation(types.ServiceConfig{
+				Name: "web", Image: "nginx",
+				Deploy:      &types.DeployConfig{Replicas: intPtr(8)},
+				Annotations: map[string]string{kubepose.HpaMaxReplicasAnnotationKey: "3"},
+			}),
+			wantErr: "must not exceed",
+		},
+		{
+			name: "zero cpu target",
+			service: withCpuReservation(types.ServiceConfig{
+				Name: "web", Image: "nginx",
+				Annotations: map[string]string{
+					kubepose.HpaMaxReplicasAnnotationKey: "3",
+					kubepose.HpaCpuAnnotationKey:         "0",
+				},
+			}),
+			wantErr: "positive integer percentage",
+		},
+		{
+			name: "combined with cronjob schedule",
+			service: withCpuReservation(types.ServiceConfig{
+				Name: "web", Image: "nginx",
+				Annotations: map[string]string{
+					kubepose.HpaMaxReplicasAnnotationKey:  "3",
+					kubepose.CronJobScheduleAnnotationKey: "0 * * * *",
+				},
+			}),
+			wantErr: "cannot be combined with",
+		},
+		{
+			name: "combined with global mode",
+			service: types.ServiceConfig{
+				Name: "web", Image: "nginx",
+				Deploy: &types.DeployConfig{
+					Mode:      "global",
+					Resources: types.Resources{Reservations: &types.Resource{NanoCPUs: 1}},
+				},
+				Annotations: map[string]string{kubepose.HpaMaxReplicasAnnotationKey: "3"},
+			},
+			wantErr: "deploy.mode: global",
+		},
+		{
+			name: "missing cpu reservation",
+			service: types.ServiceConfig{
+				Name: "web", Image: "nginx",
+				Annotations: map[string]string{kubepose.HpaMaxReplicasAnnotationKey: "3"},
+			},
+			wantErr: "requires deploy.resources.reservations.cpus",
+		},
 		{
 			name: "standalone-pod service (restart: no) is rejected, not a silent no-op",
 			service: withCpuReservation(types.ServiceConfig{
@@ -538,6 +1798,83 @@ func TestConvertHpaValidation(t *testing.T) {
 			}),
 			wantErr: "has no effect on an init container",
 		},
+		{
+			name: "behavior without maxReplicas",
+			service: types.ServiceConfig{
+				Name: "web", Image: "nginx",
+				Annotations: map[string]string{kubepose.HpaScaleDownStabilizationWindowAnnotationKey: "300"},
+			},
+			wantErr: kubepose.HpaScaleDownStabilizationWindowAnnotationKey + " has no effect without",
+		},
+		{
+			name: "stabilization window above an hour",
+			service: withCpuReservation(types.ServiceConfig{
+				Name: "web", Image: "nginx",
+				Annotations: map[string]string{
+					kubepose.HpaMaxReplicasAnnotationKey:                  "3",
+					kubepose.HpaScaleDownStabilizationWindowAnnotationKey: "7200",
+				},
+			}),
+			wantErr: "between 0 and 3600",
+		},
+		{
+			name: "malformed policies JSON",
+			service: withCpuReservation(types.ServiceConfig{
+				Name: "web", Image: "nginx",
+				Annotations: map[string]string{
+					kubepose.HpaMaxReplicasAnnotationKey:     "3",
+					kubepose.HpaScaleUpPoliciesAnnotationKey: `[{"type": "Pods", "value": 4`,
+				},
+			}),
+			wantErr: "invalid JSON policy list",
+		},
+		{
+			name: "memory resource metric",
+			service: withCpuReservation(types.ServiceConfig{
+				Name: "web", Image: "nginx",
+				Annotations: map[string]string{
+					kubepose.HpaMaxReplicasAnnotationKey: "3",
+					kubepose.HpaMetricsAnnotationKey:     `[{"type": "Resource", "resource": {"name": "memory", "target": {"type": "Utilization", "averageUtilization": 80}}}]`,
+				},
+			}),
+			wantErr: "type must be Pods, Object or External",
+		},
+		{
+			name: "Pods metric with a Value target",
+			service: types.ServiceConfig{
+				Name: "web", Image: "nginx",
+				Annotations: map[string]string{
+					kubepose.HpaMaxReplicasAnnotationKey: "3",
+					kubepose.HpaMetricsAnnotationKey:     `[{"type": "Pods", "pods": {"metric": {"name": "rps"}, "target": {"type": "Value", "value": "10"}}}]`,
+				},
+			},
+			wantErr: "Pods metrics require an AverageValue target",
+		},
+		{
+			name: "metrics in both the annotation and the extension",
+			service: types.ServiceConfig{
+				Name: "web", Image: "nginx",
+				Annotations: map[string]string{
+					kubepose.HpaMaxReplicasAnnotationKey: "3",
+					kubepose.HpaMetricsAnnotationKey:     `[]`,
+				},
+				Extensions: types.Extensions{kubepose.ServiceExtensionKey: map[string]any{
+					"hpa": map[string]any{"metrics": []any{map[string]any{"type": "External"}}},
+				}},
+			},
+			wantErr: "cannot be combined with",
+		},
+		{
+			name: "unknown extension field",
+			service: types.ServiceConfig{
+				Name: "web", Image: "nginx",
+				Annotations: map[string]string{kubepose.HpaMaxReplicasAnnotationKey: "3"},
+				Extensions: types.Extensions{kubepose.ServiceExtensionKey: map[string]any{
+					"hpa": map[string]any{"behaviour": map[string]any{}},
+				}},
+			},
+			wantErr: "unknown field",
+		},
 	}
 
 	for _, tc := range cases {
@@ -555,3 +1892,230 @@ func TestConvertHpaValidation(t *testing.T) {
 }
 
 func intPtr(i int) *int { return &i }
+
+func TestConvertServiceTypeValidation(t *testing.T) {
+	t.Parallel()
+
+	externalName := func(name string) types.Extensions {
+		return types.Extensions{kubepose.ServiceExtensionKey: map[string]any{"externalName": name}}
+	}
+
+	cases := []struct {
+		name    string
+		service types.ServiceConfig
+		wantErr string
+	}{
+		{
+			name: "unknown type",
+			service: types.ServiceConfig{
+				Name: "web", Image: "nginx",
+				Annotations: map[string]string{kubepose.ServiceTypeAnnotationKey: "Headless"},
+			},
+			wantErr: "unsupported " + kubepose.ServiceTypeAnnotationKey,
+		},
+		{
+			name: "NodePort without ports",
+			service: types.ServiceConfig{
+				Name: "web", Image: "nginx",
+				Annotations: map[string]string{kubepose.ServiceTypeAnnotationKey: "NodePort"},
+			},
+			wantErr: "requires ports",
+		},
+		{
+			name: "source ranges without LoadBalancer",
+			service: types.ServiceConfig{
+				Name: "web", Image: "nginx",
+				Ports: []types.ServicePortConfig{{Target: 80}},
+				Annotations: map[string]string{
+					kubepose.ServiceTypeAnnotationKey:                     "NodePort",
+					kubepose.ServiceLoadBalancerSourceRangesAnnotationKey: "10.0.0.0/8",
+				},
+			},
+			wantErr: "requires " + kubepose.ServiceTypeAnnotationKey + ": LoadBalancer",
+		},
+		{
+			name: "invalid source range",
+			service: types.ServiceConfig{
+				Name: "web", Image: "nginx",
+				Ports: []types.ServicePortConfig{{Target: 80}},
+				Annotations: map[string]string{
+					kubepose.ServiceTypeAnnotationKey:                     "LoadBalancer",
+					kubepose.ServiceLoadBalancerSourceRangesAnnotationKey: "10.0.0.0",
+				},
+			},
+			wantErr: "invalid CIDR",
+		},
+		{
+			name: "invalid externalTrafficPolicy",
+			service: types.ServiceConfig{
+				Name: "web", Image: "nginx",
+				Ports: []types.ServicePortConfig{{Target: 80}},
+				Annotations: map[string]string{
+					kubepose.ServiceTypeAnnotationKey:                  "LoadBalancer",
+					kubepose.ServiceExternalTrafficPolicyAnnotationKey: "Nearest",
+				},
+			},
+			wantErr: "must be Cluster or Local",
+		},
+		{
+			name: "ExternalName without externalName",
+			service: types.ServiceConfig{
+				Name: "db", Image: "postgres",
+				Annotations: map[string]string{kubepose.ServiceTypeAnnotationKey: "ExternalName"},
+			},
+			wantErr: "requires " + kubepose.ServiceExtensionKey + ".externalName",
+		},
+		{
+			name: "externalName with a conflicting type",
+			service: types.ServiceConfig{
+				Name: "db", Image: "postgres",
+				Annotations: map[string]string{kubepose.ServiceTypeAnnotationKey: "ClusterIP"},
+				Extensions:  externalName("db.example.com"),
+			},
+			wantErr: "requires " + kubepose.ServiceTypeAnnotationKey + ": ExternalName",
+		},
+		{
+			name: "externalName on a grouped service",
+			service: types.ServiceConfig{
+				Name: "db", Image: "postgres",
+				Annotations: map[string]string{kubepose.ServiceGroupAnnotationKey: "data"},
+				Extensions:  externalName("db.example.com"),
+			},
+			wantErr: "cannot be combined with an ExternalName service",
+		},
+		{
+			name: "externalName with a VPA",
+			service: types.ServiceConfig{
+				Name: "db", Image: "postgres",
+				Annotations: map[string]string{kubepose.VpaUpdateModeAnnotationKey: "Auto"},
+				Extensions:  externalName("db.example.com"),
+			},
+			wantErr: kubepose.VpaUpdateModeAnnotationKey + " cannot be combined with an ExternalName service",
+		},
+		{
+			name: "externalName with KEDA",
+			service: types.ServiceConfig{
+				Name: "db", Image: "postgres",
+				Annotations: map[string]string{kubepose.ServiceTypeAnnotationKey: "ExternalName"},
+				Extensions: types.Extensions{kubepose.ServiceExtensionKey: map[string]any{
+					"externalName": "db.example.com",
+					"keda": map[string]any{"triggers": []any{map[string]any{
+						"type": "cron", "metadata": map[string]any{"timezone": "UTC", "start": "0 8 * * *", "end": "0 18 * * *", "desiredReplicas": "1"},
+					}}},
+				}},
+			},
+			wantErr: "x-kubepose.keda cannot be combined with an ExternalName service",
+		},
+		{
+			name: "external link without a valid Service name",
+			service: types.ServiceConfig{
+				Name: "web", Image: "nginx",
+				ExternalLinks: []string{"redis.example.com"},
+			},
+			wantErr: "not a valid Service name",
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			_, err := kubepose.Transformer{}.Convert(projectWith(tc.service))
+			if err == nil {
+				t.Fatalf("expected error containing %q, got nil", tc.wantErr)
+			}
+			if !strings.Contains(err.Error(), tc.wantErr) {
+				t.Fatalf("expected error containing %q, got: %v", tc.wantErr, err)
+			}
+		})
+	}
+
+	t.Run("group members selecting different types", func(t *testing.T) {
+		t.Parallel()
+		project := &types.Project{
+			Services: types.Services{
+				"a": types.ServiceConfig{
+					Name: "a", Image: "nginx",
+					Ports: []types.ServicePortConfig{{Target: 80}},
+					Annotations: map[string]string{
+						kubepose.ServiceGroupAnnotationKey: "app",
+						kubepose.ServiceTypeAnnotationKey:  "NodePort",
+					},
+				},
+				"b": types.ServiceConfig{
+					Name: "b", Image: "nginx",
+					Ports: []types.ServicePortConfig{{Target: 81}},
+					Annotations: map[string]string{
+						kubepose.ServiceGroupAnnotationKey: "app",
+						kubepose.ServiceTypeAnnotationKey:  "LoadBalancer",
+					},
+				},
+			},
+		}
+		_, err := kubepose.Transformer{}.Convert(project)
+		if err == nil || !strings.Contains(err.Error(), "conflicting") {
+			t.Fatalf("expected conflicting type error, got: %v", err)
+		}
+	})
+}
+
+func TestConvertRegistryMirrors(t *testing.T) {
+	t.Parallel()
+
+	mirrors := map[string]string{
+		"docker.io":   "registry.local/dockerhub",
+		"ghcr.io/org": "registry.local/org",
+		"ghcr.io":     "registry.local/ghcr",
+	}
+	cases := []struct {
+		image string
+		want  string
+	}{
+		{image: "nginx", want: "registry.local/dockerhub/library/nginx"},
+		{image: "nginx:1.27", want: "registry.local/dockerhub/library/nginx:1.27"},
+		{image: "bitnami/redis:7", want: "registry.local/dockerhub/bitnami/redis:7"},
+		{image: "ghcr.io/org/app:v1", want: "registry.local/org/app:v1"},
+		{image: "ghcr.io/organization/app", want: "registry.local/ghcr/organization/app"},
+		{
+			image: "ghcr.io/org/app@sha256:0123456789abcdef0123456789abcdef0123456789abcdef0123456789abcdef",
+			want:  "registry.local/org/app@sha256:0123456789abcdef0123456789abcdef0123456789abcdef0123456789abcdef",
+		},
+		{image: "quay.io/prometheus/node-exporter", want: "quay.io/prometheus/node-exporter"},
+	}
+	for _, tc := range cases {
+		t.Run(tc.image, func(t *testing.T) {
+			t.Parallel()
+			project := projectWith(types.ServiceConfig{Name: "app", Image: tc.image, Restart: "always"})
+			resources, err := kubepose.Transformer{RegistryMirrors: mirrors}.Convert(project)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got := resources.Deployments[0].Spec.Template.Spec.Containers[0].Image; got != tc.want {
+				t.Fatalf("expected image %q, got %q", tc.want, got)
+			}
+		})
+	}
+
+	t.Run("pre_start hook images are rewritten", func(t *testing.T) {
+		t.Parallel()
+		project := projectWith(types.ServiceConfig{
+			Name: "app", Image: "ghcr.io/org/app", Restart: "always",
+			PreStart: []types.ServiceHook{{Command: types.ShellCommand{"migrate"}, Image: "busybox"}},
+		})
+		resources, err := kubepose.Transformer{RegistryMirrors: mirrors}.Convert(project)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if got := resources.Deployments[0].Spec.Template.Spec.InitContainers[0].Image; got != "registry.local/dockerhub/library/busybox" {
+			t.Fatalf("expected rewritten hook image, got %q", got)
+		}
+	})
+
+	t.Run("empty mirror target returns error", func(t *testing.T) {
+		t.Parallel()
+		project := projectWith(types.ServiceConfig{Name: "app", Image: "nginx"})
+		_, err := kubepose.Transformer{RegistryMirrors: map[string]string{"docker.io": ""}}.Convert(project)
+		if err == nil || !strings.Contains(err.Error(), "registry mirror") {
+			t.Fatalf("expected registry mirror error, got: %v", err)
+		}
+	})
+}
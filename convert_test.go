@@ -110,6 +110,60 @@ func TestConvert(t *testing.T) {
 			Files:    []string{"testdata/stop-grace-period/compose.yaml"},
 			Profiles: []string{"*"},
 		}, DryRun: TestRunKubectlDryRun | TestRunComposeDryRun},
+		{Name: "stop-signal/k8s.yaml", Options: project.Options{
+			Files:    []string{"testdata/stop-signal/compose.yaml"},
+			Profiles: []string{"*"},
+		}, DryRun: TestRunKubectlDryRun | TestRunComposeDryRun},
+		{Name: "depends-on/k8s.yaml", Options: project.Options{
+			Files:    []string{"testdata/depends-on/compose.yaml"},
+			Profiles: []string{"*"},
+		}, DryRun: TestRunKubectlDryRun | TestRunComposeDryRun},
+		{Name: "keda/k8s.yaml", Options: project.Options{
+			Files:    []string{"testdata/keda/compose.yaml"},
+			Profiles: []string{"*"},
+		}, DryRun: TestRunComposeDryRun},
+		{Name: "resources/k8s.yaml", Options: project.Options{
+			Files:    []string{"testdata/resources/compose.yaml"},
+			Profiles: []string{"*"},
+		}, DryRun: TestRunKubectlDryRun | TestRunComposeDryRun},
+		{Name: "sysctls/k8s.yaml", Options: project.Options{
+			Files:    []string{"testdata/sysctls/compose.yaml"},
+			Profiles: []string{"*"},
+		}, DryRun: TestRunKubectlDryRun | TestRunComposeDryRun},
+		{Name: "image-pull-secrets/k8s.yaml", Options: project.Options{
+			Files:    []string{"testdata/image-pull-secrets/compose.yaml"},
+			Profiles: []string{"*"},
+		}, DryRun: TestRunKubectlDryRun},
+		{Name: "file-modes/k8s.yaml", Options: project.Options{
+			Files:    []string{"testdata/file-modes/compose.yaml"},
+			Profiles: []string{"*"},
+		}, DryRun: TestRunKubectlDryRun | TestRunComposeDryRun},
+		{Name: "binary-configs/k8s.yaml", Options: project.Options{
+			Files:    []string{"testdata/binary-configs/compose.yaml"},
+			Profiles: []string{"*"},
+		}, DryRun: TestRunKubectlDryRun | TestRunComposeDryRun},
+		{Name: "external-secrets/k8s.yaml", Options: project.Options{
+			Files:    []string{"testdata/external-secrets/compose.yaml"},
+			Profiles: []string{"*"},
+		}, DryRun: TestRunComposeDryRun},
+		{Name: "sops-secrets/k8s.yaml", Options: project.Options{
+			Files:    []string{"testdata/sops-secrets/compose.yaml"},
+			Profiles: []string{"*"},
+		}, Env: map[string]string{
+			"SOPS_AGE_KEY_FILE": "testdata/sops-secrets/age.key",
+		}, DryRun: TestRunKubectlDryRun | TestRunComposeDryRun},
+		{Name: "project-defaults/k8s.yaml", Options: project.Options{
+			Files:    []string{"testdata/project-defaults/compose.yaml"},
+			Profiles: []string{"*"},
+		}, DryRun: TestRunKubectlDryRun | TestRunComposeDryRun},
+		{Name: "service-extension/k8s.yaml", Options: project.Options{
+			Files:    []string{"testdata/service-extension/compose.yaml"},
+			Profiles: []string{"*"},
+		}, DryRun: TestRunKubectlDryRun | TestRunComposeDryRun},
+		{Name: "vpa/k8s.yaml", Options: project.Options{
+			Files:    []string{"testdata/vpa/compose.yaml"},
+			Profiles: []string{"*"},
+		}, DryRun: TestRunComposeDryRun},
 		{Name: "pre-start/k8s.yaml", Options: project.Options{
 			Files:    []string{"testdata/pre-start/compose.yaml"},
 			Profiles: []string{"*"},
@@ -118,6 +172,30 @@ func TestConvert(t *testing.T) {
 			Files:    []string{"testdata/hpa/compose.yaml"},
 			Profiles: []string{"*"},
 		}, DryRun: TestRunKubectlDryRun},
+		{Name: "gateway/k8s.yaml", Options: project.Options{
+			Files:    []string{"testdata/gateway/compose.yaml"},
+			Profiles: []string{"*"},
+		}, DryRun: TestRunComposeDryRun},
+		{Name: "service-types/k8s.yaml", Options: project.Options{
+			Files:    []string{"testdata/service-types/compose.yaml"},
+			Profiles: []string{"*"},
+		}, DryRun: TestRunKubectlDryRun},
+		{Name: "ports/k8s.yaml", Options: project.Options{
+			Files:    []string{"testdata/ports/compose.yaml"},
+			Profiles: []string{"*"},
+		}, DryRun: TestRunKubectlDryRun | TestRunComposeDryRun},
+		{Name: "dns/k8s.yaml", Options: project.Options{
+			Files:    []string{"testdata/dns/compose.yaml"},
+			Profiles: []string{"*"},
+		}, DryRun: TestRunKubectlDryRun | TestRunComposeDryRun},
+		{Name: "namespaces/k8s.yaml", Options: project.Options{
+			Files:    []string{"testdata/namespaces/compose.yaml"},
+			Profiles: []string{"*"},
+		}, DryRun: TestRunKubectlDryRun | TestRunComposeDryRun},
+		{Name: "lifecycle/k8s.yaml", Options: project.Options{
+			Files:    []string{"testdata/lifecycle/compose.yaml"},
+			Profiles: []string{"*"},
+		}, DryRun: TestRunKubectlDryRun},
 	}
 	for _, tt := range tests {
 		tt := tt
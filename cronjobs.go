@@ -1,10 +1,16 @@
 package kubepose
 
 import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
 	"github.com/compose-spec/compose-go/v2/types"
 	batchv1 "k8s.io/api/batch/v1"
 	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/utils/ptr"
 )
 
 func (t Transformer) createCronJob(resources *Resources, service types.ServiceConfig) *batchv1.CronJob {
@@ -55,6 +61,205 @@ func (t Transformer) createCronJob(resources *Resources, service types.ServiceCo
 			},
 		},
 	}
+	applyCronJobControls(&c.Spec, service)
 	resources.CronJobs = append(resources.CronJobs, c)
 	return c
 }
+
+// applyCronJobControls sets the optional kubepose.cronjob.* settings on the
+// CronJob and its Job template. Values are validated in validateService;
+// parse errors cannot occur here.
+func applyCronJobControls(spec *batchv1.CronJobSpec, service types.ServiceConfig) {
+	annotations := service.Annotations
+	if value, ok := annotations[CronJobConcurrencyPolicyAnnotationKey]; ok {
+		spec.ConcurrencyPolicy, _ = parseConcurrencyPolicy(value)
+	}
+	if value, ok := annotations[CronJobTimeZoneAnnotationKey]; ok {
+		spec.TimeZone = ptr.To(value)
+	}
+	if value, ok := annotations[CronJobSuspendAnnotationKey]; ok {
+		suspend, _ := strconv.ParseBool(value)
+		spec.Suspend = ptr.To(suspend)
+	}
+	int32Value := func(key string) *int32 {
+		value, ok := annotations[key]
+		if !ok {
+			return nil
+		}
+		n, _ := strconv.ParseInt(value, 10, 32)
+		return ptr.To(int32(n))
+	}
+	int64Value := func(key string) *int64 {
+		value, ok := annotations[key]
+		if !ok {
+			return nil
+		}
+		n, _ := strconv.ParseInt(value, 10, 64)
+		return ptr.To(n)
+	}
+	spec.StartingDeadlineSeconds = int64Value(CronJobStartingDeadlineSecondsAnnotationKey)
+	spec.SuccessfulJobsHistoryLimit = int32Value(CronJobSuccessfulJobsHistoryLimitAnnotationKey)
+	spec.FailedJobsHistoryLimit = int32Value(CronJobFailedJobsHistoryLimitAnnotationKey)
+	spec.JobTemplate.Spec.BackoffLimit = int32Value(CronJobBackoffLimitAnnotationKey)
+	spec.JobTemplate.Spec.ActiveDeadlineSeconds = int64Value(CronJobActiveDeadlineSecondsAnnotationKey)
+}
+
+// cronJobControlAnnotationKeys are the CronJob settings that only apply
+// together with kubepose.cronjob.schedule.
+var cronJobControlAnnotationKeys = []string{
+	CronJobConcurrencyPolicyAnnotationKey,
+	CronJobTimeZoneAnnotationKey,
+	CronJobSuspendAnnotationKey,
+	CronJobStartingDeadlineSecondsAnnotationKey,
+	CronJobSuccessfulJobsHistoryLimitAnnotationKey,
+	CronJobFailedJobsHistoryLimitAnnotationKey,
+	CronJobBackoffLimitAnnotationKey,
+	CronJobActiveDeadlineSecondsAnnotationKey,
+}
+
+// validateCronJob rejects schedules and CronJob settings Kubernetes would
+// refuse on apply, and CronJob settings on services that are not CronJobs.
+// Called from validateService.
+func validateCronJob(service types.ServiceConfig) error {
+	schedule, isCronJob := service.Annotations[CronJobScheduleAnnotationKey]
+	if !isCronJob {
+		for _, key := range cronJobControlAnnotationKeys {
+			if _, ok := service.Annotations[key]; ok {
+				return fmt.Errorf("%s has no effect without %s", key, CronJobScheduleAnnotationKey)
+			}
+		}
+		return nil
+	}
+	if schedule == "" {
+		return fmt.Errorf("%s must not be empty", CronJobScheduleAnnotationKey)
+	}
+	if err := validateCronSchedule(schedule); err != nil {
+		return fmt.Errorf("%s %q: %w", CronJobScheduleAnnotationKey, schedule, err)
+	}
+
+	if value, ok := service.Annotations[CronJobConcurrencyPolicyAnnotationKey]; ok {
+		if _, err := parseConcurrencyPolicy(value); err != nil {
+			return err
+		}
+	}
+	if value, ok := service.Annotations[CronJobTimeZoneAnnotationKey]; ok {
+		// Kubernetes resolves the zone on the controller, where "Local" is
+		// whatever the control plane runs in.
+		if _, err := time.LoadLocation(value); err != nil || value == "" || value == "Local" {
+			return fmt.Errorf("%s %q: expected an IANA time zone such as \"Europe/Stockholm\"", CronJobTimeZoneAnnotationKey, value)
+		}
+	}
+	if value, ok := service.Annotations[CronJobSuspendAnnotationKey]; ok {
+		if _, err := strconv.ParseBool(value); err != nil {
+			return fmt.Errorf("%s must be true or false, got %q", CronJobSuspendAnnotationKey, value)
+		}
+	}
+	for _, key := range []string{
+		CronJobStartingDeadlineSecondsAnnotationKey,
+		CronJobSuccessfulJobsHistoryLimitAnnotationKey,
+		CronJobFailedJobsHistoryLimitAnnotationKey,
+		CronJobBackoffLimitAnnotationKey,
+	} {
+		if value, ok := service.Annotations[key]; ok {
+			if n, err := strconv.ParseInt(value, 10, 32); err != nil || n < 0 {
+				return fmt.Errorf("%s must be a non-negative integer, got %q", key, value)
+			}
+		}
+	}
+	if value, ok := service.Annotations[CronJobActiveDeadlineSecondsAnnotationKey]; ok {
+		if n, err := strconv.ParseInt(value, 10, 64); err != nil || n < 1 {
+			return fmt.Errorf("%s must be a positive integer, got %q", CronJobActiveDeadlineSecondsAnnotationKey, value)
+		}
+	}
+	return nil
+}
+
+func parseConcurrencyPolicy(value string) (batchv1.ConcurrencyPolicy, error) {
+	for _, policy := range []batchv1.ConcurrencyPolicy{batchv1.AllowConcurrent, batchv1.ForbidConcurrent, batchv1.ReplaceConcurrent} {
+		if strings.EqualFold(value, string(policy)) {
+			return policy, nil
+		}
+	}
+	return "", fmt.Errorf("%s %q: expected Allow, Forbid or Replace", CronJobConcurrencyPolicyAnnotationKey, value)
+}
+
+// cronFields are the bounds of the five standard cron fields, with the names
+// accepted in the month and day-of-week fields.
+var cronFields = []struct {
+	name     string
+	min, max int
+	names    []string
+}{
+	{"minute", 0, 59, nil},
+	{"hour", 0, 23, nil},
+	{"day of month", 1, 31, nil},
+	{"month", 1, 12, []string{"JAN", "FEB", "MAR", "APR", "MAY", "JUN", "JUL", "AUG", "SEP", "OCT", "NOV", "DEC"}},
+	{"day of week", 0, 6, []string{"SUN", "MON", "TUE", "WED", "THU", "FRI", "SAT"}},
+}
+
+// validateCronSchedule checks a schedule against the standard five-field
+// cron syntax and descriptors the CronJob controller accepts. Time zones go
+// in kubepose.cronjob.timeZone, since Kubernetes rejects TZ= prefixes.
+func validateCronSchedule(schedule string) error {
+	if strings.HasPrefix(schedule, "TZ=") || strings.HasPrefix(schedule, "CRON_TZ=") {
+		return fmt.Errorf("time zone prefixes are not supported, use %s", CronJobTimeZoneAnnotationKey)
+	}
+	if strings.HasPrefix(schedule, "@") {
+		switch schedule {
+		case "@yearly", "@annually", "@monthly", "@weekly", "@daily", "@midnight", "@hourly":
+			return nil
+		}
+		if every, ok := strings.CutPrefix(schedule, "@every "); ok {
+			if d, err := time.ParseDuration(every); err == nil && d > 0 {
+				return nil
+			}
+		}
+		return fmt.Errorf("unknown descriptor")
+	}
+
+	fields := strings.Fields(schedule)
+	if len(fields) != len(cronFields) {
+		return fmt.Errorf("expected 5 fields (minute hour day-of-month month day-of-week), got %d", len(fields))
+	}
+	for i, field := range fields {
+		spec := cronFields[i]
+		parse := func(value string) (int, error) {
+			for n, name := range spec.names {
+				if strings.EqualFold(value, name) {
+					return n + spec.min, nil
+				}
+			}
+			n, err := strconv.Atoi(value)
+			if err != nil || n < spec.min || n > spec.max {
+				return 0, fmt.Errorf("%s %q: expected %d-%d", spec.name, value, spec.min, spec.max)
+			}
+			return n, nil
+		}
+		for _, item := range strings.Split(field, ",") {
+			rangeValue, step, hasStep := strings.Cut(item, "/")
+			if hasStep {
+				if n, err := strconv.Atoi(step); err != nil || n < 1 {
+					return fmt.Errorf("%s step %q: expected a positive integer", spec.name, step)
+				}
+			}
+			if rangeValue == "*" || (rangeValue == "?" && (i == 2 || i == 4)) {
+				continue
+			}
+			start, end, isRange := strings.Cut(rangeValue, "-")
+			first, err := parse(start)
+			if err != nil {
+				return err
+			}
+			if isRange {
+				last, err := parse(end)
+				if err != nil {
+					return err
+				}
+				if last < first {
+					return fmt.Errorf("%s range %q: start is after end", spec.name, rangeValue)
+				}
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,135 @@
+package kubepose
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/compose-spec/compose-go/v2/types"
+	corev1 "k8s.io/api/core/v1"
+)
+
+// Values for Transformer.DependsOn and kubepose.dependsOn.
+const (
+	DependsOnIgnore = "ignore"
+	DependsOnWait   = "wait"
+)
+
+// dependsOnWaitImage runs the wait init containers; busybox ships both nc
+// and nslookup.
+const dependsOnWaitImage = "busybox:1.37"
+
+// dependsOnMode returns whether a service's depends_on is ignored or turned
+// into wait init containers, preferring the service annotation over the
+// transformer-wide default.
+func (t Transformer) dependsOnMode(service types.ServiceConfig) string {
+	if mode, ok := service.Annotations[DependsOnAnnotationKey]; ok {
+		return mode
+	}
+	if t.DependsOn == "" {
+		return DependsOnIgnore
+	}
+	return t.DependsOn
+}
+
+// waitDependencies returns the names of the dependencies a service waits for,
+// sorted for stable output. Only service_started and service_healthy
+// conditions are waited for: service_completed_successfully has no
+// Kubernetes Service to probe and is expressed with pre_start hooks instead.
+// Dependencies in the same pod are skipped, since its containers start
+// together and an init container waiting on them would never finish.
+func (t Transformer) waitDependencies(project *types.Project, service types.ServiceConfig) []string {
+	if t.dependsOnMode(service) != DependsOnWait {
+		return nil
+	}
+	var names []string
+	for name, dependency := range service.DependsOn {
+		switch dependency.Condition {
+		case types.ServiceConditionStarted, types.ServiceConditionHealthy:
+		default:
+			continue
+		}
+		target, ok := project.Services[name]
+		if !ok {
+			// An optional dependency that is not part of the project.
+			continue
+		}
+		if isPodGroupMember(service) && isPodGroupMember(target) && getServiceName(target) == getServiceName(service) {
+			continue
+		}
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// validateDependsOn rejects depends_on modes kubepose does not know and, in
+// wait mode, dependencies that get no Kubernetes Service to wait for. Called
+// from Convert, since it needs the other services and the transformer-wide
+// default.
+func (t Transformer) validateDependsOn(project *types.Project, service types.ServiceConfig) error {
+	if mode, ok := service.Annotations[DependsOnAnnotationKey]; ok && mode != DependsOnIgnore && mode != DependsOnWait {
+		return fmt.Errorf("%s %q: expected %q or %q", DependsOnAnnotationKey, mode, DependsOnIgnore, DependsOnWait)
+	}
+	for _, name := range t.waitDependencies(project, service) {
+		if !hasKubernetesService(project.Services[name]) {
+			return fmt.Errorf("depends_on %q: service %q gets no Kubernetes Service to wait for (it converts to a standalone Pod or a portless CronJob); use condition: service_completed_successfully or drop %s: %s", name, name, DependsOnAnnotationKey, DependsOnWait)
+		}
+	}
+	return nil
+}
+
+// hasKubernetesService reports whether Convert emits a Service for a compose
+// service, mirroring its branches.
+func hasKubernetesService(service types.ServiceConfig) bool {
+	if getServiceType(service) == corev1.ServiceTypeExternalName {
+		return true
+	}
+	if !isPodGroupMember(service) {
+		return false
+	}
+	_, isCronJob := service.Annotations[CronJobScheduleAnnotationKey]
+	return !isCronJob || len(service.Ports) > 0 || len(service.Expose) > 0
+}
+
+// addDependsOnWaitContainers prepends an init container per dependency that
+// blocks until the dependency's Service accepts TCP connections on its first
+// TCP port. A ClusterIP Service only forwards to ready endpoints, so this
+// also honours service_healthy through the dependency's readiness probe.
+// Portless dependencies have a headless Service, whose DNS record only
+// exists while an endpoint is ready, so those wait for DNS instead. Waits
+// run before pre_start hooks, which commonly need the dependency themselves.
+func (t Transformer) addDependsOnWaitContainers(podSpec *corev1.PodSpec, project *types.Project, services []types.ServiceConfig) {
+	var containers []corev1.Container
+	seen := make(map[string]bool)
+	for _, c := range podSpec.InitContainers {
+		seen[c.Name] = true
+	}
+	for _, service := range services {
+		for _, name := range t.waitDependencies(project, service) {
+			containerName := "wait-for-" + name
+			if seen[containerName] {
+				continue
+			}
+			seen[containerName] = true
+			containers = append(containers, t.createWaitContainer(containerName, project.Services[name]))
+		}
+	}
+	podSpec.InitContainers = append(containers, podSpec.InitContainers...)
+}
+
+func (t Transformer) createWaitContainer(name string, dependency types.ServiceConfig) corev1.Container {
+	host := getServiceName(dependency)
+	script := fmt.Sprintf("until nslookup %[1]s; do echo waiting for %[1]s; sleep 2; done", host)
+	for _, port := range t.createService(dependency).Spec.Ports {
+		if port.Protocol == corev1.ProtocolTCP {
+			script = fmt.Sprintf("until nc -z -w 2 %[1]s %[2]d; do echo waiting for %[1]s:%[2]d; sleep 2; done", host, port.Port)
+			break
+		}
+	}
+	return corev1.Container{
+		Name:            name,
+		Image:           t.rewriteImage(dependsOnWaitImage),
+		Command:         []string{"sh", "-c", script},
+		ImagePullPolicy: corev1.PullIfNotPresent,
+	}
+}
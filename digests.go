@@ -0,0 +1,79 @@
+package kubepose
+
+import (
+	"fmt"
+
+	"github.com/distribution/reference"
+	corev1 "k8s.io/api/core/v1"
+)
+
+// DigestResolver resolves an image reference's tag to the manifest digest
+// ("sha256:...") it currently points to.
+type DigestResolver interface {
+	ResolveDigest(image string) (string, error)
+}
+
+// pinDigests rewrites every image in the converted pod templates, including
+// init containers and image volumes, to reference the digest its tag
+// resolves to, so re-applying an unchanged manifest cannot roll out new
+// code. It runs after conversion, on the references the cluster will pull
+// (i.e. after registry mirror rewriting).
+func (t Transformer) pinDigests(resources *Resources) error {
+	var specs []*corev1.PodSpec
+	for _, pod := range resources.Pods {
+		specs = append(specs, &pod.Spec)
+	}
+	for _, deployment := range resources.Deployments {
+		specs = append(specs, &deployment.Spec.Template.Spec)
+	}
+	for _, ds := range resources.DaemonSets {
+		specs = append(specs, &ds.Spec.Template.Spec)
+	}
+	for _, cronJob := range resources.CronJobs {
+		specs = append(specs, &cronJob.Spec.JobTemplate.Spec.Template.Spec)
+	}
+	for _, scaledJob := range resources.ScaledJobs {
+		specs = append(specs, &scaledJob.Spec.JobTargetRef.Template.Spec)
+	}
+
+	for _, spec := range specs {
+		for _, containers := range [][]corev1.Container{spec.InitContainers, spec.Containers} {
+			for i := range containers {
+				pinned, err := t.pinDigest(containers[i].Image)
+				if err != nil {
+					return fmt.Errorf("container %q: %w", containers[i].Name, err)
+				}
+				containers[i].Image = pinned
+			}
+		}
+		for i := range spec.Volumes {
+			if spec.Volumes[i].Image == nil {
+				continue
+			}
+			pinned, err := t.pinDigest(spec.Volumes[i].Image.Reference)
+			if err != nil {
+				return fmt.Errorf("volume %q: %w", spec.Volumes[i].Name, err)
+			}
+			spec.Volumes[i].Image.Reference = pinned
+		}
+	}
+	return nil
+}
+
+// pinDigest appends the resolved digest to an image reference, keeping the
+// tag for readability. References already carrying a digest are kept as
+// written.
+func (t Transformer) pinDigest(image string) (string, error) {
+	named, err := reference.ParseNormalizedNamed(image)
+	if err != nil {
+		return "", fmt.Errorf("image %q: %w", image, err)
+	}
+	if _, ok := named.(reference.Digested); ok {
+		return image, nil
+	}
+	digest, err := t.DigestResolver.ResolveDigest(image)
+	if err != nil {
+		return "", err
+	}
+	return image + "@" + digest, nil
+}
@@ -0,0 +1,123 @@
+package kubepose
+
+import (
+	"fmt"
+	"net"
+	"reflect"
+	"strings"
+
+	"github.com/compose-spec/compose-go/v2/types"
+	"github.com/sirupsen/logrus"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/util/validation"
+)
+
+// Kubernetes limits on pod dnsConfig, checked upfront so the manifest is not
+// rejected on apply.
+const (
+	maxDNSNameservers = 3
+	maxDNSSearches    = 32
+)
+
+// getDNSConfig converts compose dns, dns_search and dns_opt into a pod DNS
+// policy and config. Custom nameservers replace the cluster resolver
+// (dnsPolicy None); search domains and options alone are appended to the
+// default ClusterFirst configuration.
+func getDNSConfig(service types.ServiceConfig) (corev1.DNSPolicy, *corev1.PodDNSConfig) {
+	if len(service.DNS) == 0 && len(service.DNSSearch) == 0 && len(service.DNSOpts) == 0 {
+		return "", nil
+	}
+
+	config := &corev1.PodDNSConfig{
+		Nameservers: service.DNS,
+		Searches:    service.DNSSearch,
+	}
+	for _, opt := range service.DNSOpts {
+		name, value, hasValue := strings.Cut(opt, ":")
+		option := corev1.PodDNSConfigOption{Name: name}
+		if hasValue {
+			option.Value = &value
+		}
+		config.Options = append(config.Options, option)
+	}
+
+	var policy corev1.DNSPolicy
+	if len(service.DNS) > 0 {
+		policy = corev1.DNSNone
+	}
+	return policy, config
+}
+
+// validateDNS rejects DNS settings Kubernetes cannot represent. Called from
+// validateService.
+func validateDNS(service types.ServiceConfig) error {
+	for _, ns := range service.DNS {
+		if net.ParseIP(ns) == nil {
+			return fmt.Errorf("dns %q: Kubernetes only accepts nameserver IP addresses", ns)
+		}
+	}
+	if len(service.DNS) > maxDNSNameservers {
+		return fmt.Errorf("dns: at most %d nameservers are supported, got %d", maxDNSNameservers, len(service.DNS))
+	}
+	if len(service.DNSSearch) > maxDNSSearches {
+		return fmt.Errorf("dns_search: at most %d search domains are supported, got %d", maxDNSSearches, len(service.DNSSearch))
+	}
+	for _, opt := range service.DNSOpts {
+		if name, _, _ := strings.Cut(opt, ":"); name == "" {
+			return fmt.Errorf("dns_opt %q: option name must not be empty", opt)
+		}
+	}
+	if service.Hostname != "" {
+		if errs := validation.IsDNS1123Label(service.Hostname); len(errs) > 0 {
+			return fmt.Errorf("hostname %q: %s", service.Hostname, strings.Join(errs, ", "))
+		}
+	}
+	if service.DomainName != "" {
+		// The pod subdomain is a single label; the cluster appends the
+		// namespace and cluster domain to it.
+		if errs := validation.IsDNS1123Label(service.DomainName); len(errs) > 0 {
+			return fmt.Errorf("domainname %q: only a single DNS label is supported as the pod subdomain: %s", service.DomainName, strings.Join(errs, ", "))
+		}
+	}
+
+	// Every replica shares the pod template, so they would all claim the
+	// same hostname. compose does the same with replicas, so this is only
+	// flagged rather than rejected.
+	multiReplica := hasHorizontalPodAutoscaler(service) || hasKeda(service) ||
+		(service.Deploy != nil && (service.Deploy.Mode == "global" || (service.Deploy.Replicas != nil && *service.Deploy.Replicas > 1)))
+	if service.Hostname != "" && multiReplica {
+		logrus.Warnf("service %q: hostname %q is shared by every replica", service.Name, service.Hostname)
+	}
+	return nil
+}
+
+// mergePodDNS folds a grouped service's DNS, hostname and subdomain settings
+// into the shared pod spec. Pod-level settings can only be set once, so a
+// member declaring a different value than the pod already carries is an
+// error rather than being silently dropped.
+func mergePodDNS(podSpec *corev1.PodSpec, service types.ServiceConfig) error {
+	policy, config := getDNSConfig(service)
+	if config != nil {
+		if podSpec.DNSConfig == nil {
+			podSpec.DNSPolicy = policy
+			podSpec.DNSConfig = config
+		} else if podSpec.DNSPolicy != policy || !reflect.DeepEqual(podSpec.DNSConfig, config) {
+			return fmt.Errorf("service %q: dns, dns_search and dns_opt conflict with another service in the same pod", service.Name)
+		}
+	}
+	if service.Hostname != "" {
+		if podSpec.Hostname == "" {
+			podSpec.Hostname = service.Hostname
+		} else if podSpec.Hostname != service.Hostname {
+			return fmt.Errorf("service %q: hostname %q conflicts with %q from another service in the same pod", service.Name, service.Hostname, podSpec.Hostname)
+		}
+	}
+	if service.DomainName != "" {
+		if podSpec.Subdomain == "" {
+			podSpec.Subdomain = service.DomainName
+		} else if podSpec.Subdomain != service.DomainName {
+			return fmt.Errorf("service %q: domainname %q conflicts with %q from another service in the same pod", service.Name, service.DomainName, podSpec.Subdomain)
+		}
+	}
+	return nil
+}
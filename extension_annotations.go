@@ -0,0 +1,200 @@
+package kubepose
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/compose-spec/compose-go/v2/types"
+)
+
+// extensionAnnotations translates the typed fields of a service's x-kubepose
+// extension into the kubepose annotations they alias, so the converter reads
+// a single source. The annotations remain supported; setting a field and its
+// annotation both is an error.
+func extensionAnnotations(service types.ServiceConfig) (map[string]string, error) {
+	ext, err := getServiceExtension(service)
+	if err != nil {
+		return nil, err
+	}
+	if unknown := unknownExtensionFields(reflect.ValueOf(ext), ServiceExtensionKey); len(unknown) > 0 {
+		return nil, fmt.Errorf("unknown field %s", strings.Join(unknown, ", "))
+	}
+
+	annotations := map[string]string{}
+	fields := map[string]string{}
+	set := func(key, field, value string) {
+		annotations[key] = value
+		fields[key] = field
+	}
+	setInt := func(key, field string, value *int64) {
+		if value != nil {
+			set(key, field, strconv.FormatInt(*value, 10))
+		}
+	}
+	int32Ptr := func(value *int32) *int64 {
+		if value == nil {
+			return nil
+		}
+		v := int64(*value)
+		return &v
+	}
+	setString := func(key, field, value string) {
+		if value != "" {
+			set(key, field, value)
+		}
+	}
+
+	setString(ServiceGroupAnnotationKey, "group", ext.Group)
+	setString(ServiceAccountNameAnnotationKey, "serviceAccountName", ext.ServiceAccountName)
+	setString(DependsOnAnnotationKey, "dependsOn", ext.DependsOn)
+	if ext.Selector != nil && ext.Selector.MatchLabels != nil {
+		data, err := json.Marshal(ext.Selector.MatchLabels)
+		if err != nil {
+			return nil, err
+		}
+		set(SelectorMatchLabelsAnnotationKey, "selector.matchLabels", string(data))
+	}
+	if e := ext.Expose; e != nil {
+		if len(e.Hosts) > 0 {
+			set(ServiceExposeAnnotationKey, "expose.hosts", strings.Join(e.Hosts, ","))
+		} else {
+			set(ServiceExposeAnnotationKey, "expose", "true")
+		}
+		setString(ServiceExposeIngressClassNameAnnotationKey, "expose.ingressClassName", e.IngressClassName)
+		setString(ServiceExposeGatewayAnnotationKey, "expose.gateway", e.Gateway)
+		if e.Port != nil {
+			port, err := scalarString(e.Port)
+			if err != nil {
+				return nil, fmt.Errorf("%s.expose.port: %w (expected a port name or number)", ServiceExtensionKey, err)
+			}
+			set(ServiceExposePortAnnotationKey, "expose.port", port)
+		}
+	}
+	if e := ext.Healthcheck; e != nil && e.HTTPGet != nil {
+		setString(HealthcheckHttpGetPathAnnotationKey, "healthcheck.httpGet.path", e.HTTPGet.Path)
+		if e.HTTPGet.Port != nil {
+			port, err := scalarString(e.HTTPGet.Port)
+			if err != nil {
+				return nil, fmt.Errorf("%s.healthcheck.httpGet.port: %w (expected a port name or number)", ServiceExtensionKey, err)
+			}
+			set(HealthcheckHttpGetPortAnnotationKey, "healthcheck.httpGet.port", port)
+		}
+	}
+	if e := ext.Container; e != nil {
+		setString(ContainerTypeAnnotationKey, "container.type", e.Type)
+	}
+	if e := ext.Service; e != nil {
+		setString(ServiceTypeAnnotationKey, "service.type", e.Type)
+		if len(e.LoadBalancerSourceRanges) > 0 {
+			set(ServiceLoadBalancerSourceRangesAnnotationKey, "service.loadBalancerSourceRanges", strings.Join(e.LoadBalancerSourceRanges, ","))
+		}
+		setString(ServiceLoadBalancerClassAnnotationKey, "service.loadBalancerClass", e.LoadBalancerClass)
+		setString(ServiceExternalTrafficPolicyAnnotationKey, "service.externalTrafficPolicy", e.ExternalTrafficPolicy)
+	}
+	if e := ext.Lifecycle; e != nil && e.PreStop != nil && e.PreStop.Sleep != nil {
+		sleep, err := scalarString(e.PreStop.Sleep)
+		if err != nil {
+			return nil, fmt.Errorf("%s.lifecycle.preStop.sleep: %w (expected a duration or seconds)", ServiceExtensionKey, err)
+		}
+		set(LifecyclePreStopSleepAnnotationKey, "lifecycle.preStop.sleep", sleep)
+	}
+	if e := ext.CronJob; e != nil {
+		setString(CronJobScheduleAnnotationKey, "cronjob.schedule", e.Schedule)
+		setString(CronJobConcurrencyPolicyAnnotationKey, "cronjob.concurrencyPolicy", e.ConcurrencyPolicy)
+		setString(CronJobTimeZoneAnnotationKey, "cronjob.timeZone", e.TimeZone)
+		if e.Suspend != nil {
+			set(CronJobSuspendAnnotationKey, "cronjob.suspend", strconv.FormatBool(*e.Suspend))
+		}
+		setInt(CronJobStartingDeadlineSecondsAnnotationKey, "cronjob.startingDeadlineSeconds", e.StartingDeadlineSeconds)
+		setInt(CronJobSuccessfulJobsHistoryLimitAnnotationKey, "cronjob.successfulJobsHistoryLimit", int32Ptr(e.SuccessfulJobsHistoryLimit))
+		setInt(CronJobFailedJobsHistoryLimitAnnotationKey, "cronjob.failedJobsHistoryLimit", int32Ptr(e.FailedJobsHistoryLimit))
+		setInt(CronJobBackoffLimitAnnotationKey, "cronjob.backoffLimit", int32Ptr(e.BackoffLimit))
+		setInt(CronJobActiveDeadlineSecondsAnnotationKey, "cronjob.activeDeadlineSeconds", e.ActiveDeadlineSeconds)
+	}
+	if ext.HPA != nil {
+		var hpa hpaExtension
+		if err := decodeKubernetesValue(ext.HPA, &hpa); err != nil {
+			return nil, fmt.Errorf("invalid %s.hpa: %w", ServiceExtensionKey, err)
+		}
+		setInt(HpaMaxReplicasAnnotationKey, "hpa.maxReplicas", int32Ptr(hpa.MaxReplicas))
+		setInt(HpaMinReplicasAnnotationKey, "hpa.minReplicas", int32Ptr(hpa.MinReplicas))
+		setInt(HpaCpuAnnotationKey, "hpa.cpu", int32Ptr(hpa.CPU))
+	}
+	if e := ext.VPA; e != nil {
+		setString(VpaUpdateModeAnnotationKey, "vpa.updateMode", e.UpdateMode)
+	}
+	if e := ext.Resources; e != nil {
+		setString(GpuResourceNameAnnotationKey, "resources.gpu", e.GPU)
+	}
+	if e := ext.Pod; e != nil && len(e.ImagePullSecrets) > 0 {
+		set(ImagePullSecretsAnnotationKey, "pod.imagePullSecrets", strings.Join(e.ImagePullSecrets, ","))
+	}
+
+	for _, key := range sortedKeys(annotations) {
+		if _, ok := service.Annotations[key]; ok {
+			return nil, fmt.Errorf("%s.%s and the %s annotation cannot both be set", ServiceExtensionKey, fields[key], key)
+		}
+	}
+	return annotations, nil
+}
+
+// scalarString formats a string or integer extension value.
+func scalarString(value any) (string, error) {
+	switch v := value.(type) {
+	case string:
+		return v, nil
+	case int, int32, int64, uint64:
+		return fmt.Sprint(v), nil
+	}
+	return "", fmt.Errorf("unexpected %T value", value)
+}
+
+// unknownExtensionFields returns the dotted paths of extension keys that
+// mapstructure collected in an Unknown field instead of a typed one.
+func unknownExtensionFields(v reflect.Value, path string) []string {
+	for v.Kind() == reflect.Pointer {
+		if v.IsNil() {
+			return nil
+		}
+		v = v.Elem()
+	}
+	if v.Kind() != reflect.Struct {
+		return nil
+	}
+	var unknown []string
+	for i := 0; i < v.NumField(); i++ {
+		field := v.Type().Field(i)
+		if field.Name == "Unknown" {
+			for _, key := range v.Field(i).MapKeys() {
+				unknown = append(unknown, path+"."+key.String())
+			}
+			continue
+		}
+		name, _, _ := strings.Cut(field.Tag.Get("mapstructure"), ",")
+		unknown = append(unknown, unknownExtensionFields(v.Field(i), path+"."+name)...)
+	}
+	sort.Strings(unknown)
+	return unknown
+}
+
+// applyServiceExtensions returns a copy of project whose services carry the
+// annotations aliased by their x-kubepose fields.
+func applyServiceExtensions(project *types.Project) (*types.Project, error) {
+	copied := *project
+	copied.Services = make(types.Services, len(project.Services))
+	for name, service := range project.Services {
+		annotations, err := extensionAnnotations(service)
+		if err != nil {
+			return nil, fmt.Errorf("service %q: %w", name, err)
+		}
+		if len(annotations) > 0 {
+			service.Annotations = mergeMaps(service.Annotations, annotations)
+		}
+		copied.Services[name] = service
+	}
+	return &copied, nil
+}
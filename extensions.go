@@ -0,0 +1,155 @@
+package kubepose
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+
+	"github.com/compose-spec/compose-go/v2/types"
+)
+
+// serviceExtension holds the settings read from a service's x-kubepose
+// extension. Besides the values that have no natural string annotation form,
+// every kubepose.* service annotation has a typed field here; see
+// extensionAnnotations for the mapping.
+type serviceExtension struct {
+	// ExternalName turns the service into an ExternalName Service pointing
+	// at this DNS name; no workload is emitted for it.
+	ExternalName string `mapstructure:"externalName"`
+	// HPA holds HorizontalPodAutoscaler replica bounds, behavior and metrics
+	// in their Kubernetes shape; see hpaExtension.
+	HPA any `mapstructure:"hpa"`
+	// KEDA enables KEDA event-driven scaling; see kedaExtension.
+	KEDA any `mapstructure:"keda"`
+
+	Group              string                `mapstructure:"group"`
+	ServiceAccountName string                `mapstructure:"serviceAccountName"`
+	DependsOn          string                `mapstructure:"dependsOn"`
+	Selector           *selectorExtension    `mapstructure:"selector"`
+	Expose             *exposeExtension      `mapstructure:"expose"`
+	Healthcheck        *healthcheckExtension `mapstructure:"healthcheck"`
+	Container          *containerExtension   `mapstructure:"container"`
+	Service            *serviceTypeExtension `mapstructure:"service"`
+	Lifecycle          *lifecycleExtension   `mapstructure:"lifecycle"`
+	CronJob            *cronJobExtension     `mapstructure:"cronjob"`
+	VPA                *vpaExtension         `mapstructure:"vpa"`
+	Resources          *resourcesExtension   `mapstructure:"resources"`
+	Pod                *podExtension         `mapstructure:"pod"`
+	Unknown            map[string]any        `mapstructure:",remain"`
+}
+
+type selectorExtension struct {
+	MatchLabels map[string]string `mapstructure:"matchLabels"`
+	Unknown     map[string]any    `mapstructure:",remain"`
+}
+
+type exposeExtension struct {
+	// Hosts defaults to the service name.
+	Hosts            []string `mapstructure:"hosts"`
+	IngressClassName string   `mapstructure:"ingressClassName"`
+	Gateway          string   `mapstructure:"gateway"`
+	// Port is a compose port name or published port number.
+	Port    any            `mapstructure:"port"`
+	Unknown map[string]any `mapstructure:",remain"`
+}
+
+type healthcheckExtension struct {
+	HTTPGet *struct {
+		Path    string         `mapstructure:"path"`
+		Port    any            `mapstructure:"port"`
+		Unknown map[string]any `mapstructure:",remain"`
+	} `mapstructure:"httpGet"`
+	Unknown map[string]any `mapstructure:",remain"`
+}
+
+type containerExtension struct {
+	Type    string         `mapstructure:"type"`
+	Unknown map[string]any `mapstructure:",remain"`
+}
+
+type serviceTypeExtension struct {
+	Type                     string         `mapstructure:"type"`
+	LoadBalancerSourceRanges []string       `mapstructure:"loadBalancerSourceRanges"`
+	LoadBalancerClass        string         `mapstructure:"loadBalancerClass"`
+	ExternalTrafficPolicy    string         `mapstructure:"externalTrafficPolicy"`
+	Unknown                  map[string]any `mapstructure:",remain"`
+}
+
+type lifecycleExtension struct {
+	PreStop *struct {
+		// Sleep is a duration ("5s") or a number of seconds.
+		Sleep   any            `mapstructure:"sleep"`
+		Unknown map[string]any `mapstructure:",remain"`
+	} `mapstructure:"preStop"`
+	Unknown map[string]any `mapstructure:",remain"`
+}
+
+type cronJobExtension struct {
+	Schedule                   string         `mapstructure:"schedule"`
+	ConcurrencyPolicy          string         `mapstructure:"concurrencyPolicy"`
+	TimeZone                   string         `mapstructure:"timeZone"`
+	Suspend                    *bool          `mapstructure:"suspend"`
+	StartingDeadlineSeconds    *int64         `mapstructure:"startingDeadlineSeconds"`
+	SuccessfulJobsHistoryLimit *int32         `mapstructure:"successfulJobsHistoryLimit"`
+	FailedJobsHistoryLimit     *int32         `mapstructure:"failedJobsHistoryLimit"`
+	BackoffLimit               *int32         `mapstructure:"backoffLimit"`
+	ActiveDeadlineSeconds      *int64         `mapstructure:"activeDeadlineSeconds"`
+	Unknown                    map[string]any `mapstructure:",remain"`
+}
+
+type vpaExtension struct {
+	UpdateMode string         `mapstructure:"updateMode"`
+	Unknown    map[string]any `mapstructure:",remain"`
+}
+
+type resourcesExtension struct {
+	GPU     string         `mapstructure:"gpu"`
+	Unknown map[string]any `mapstructure:",remain"`
+}
+
+type podExtension struct {
+	ImagePullSecrets []string       `mapstructure:"imagePullSecrets"`
+	Unknown          map[string]any `mapstructure:",remain"`
+}
+
+// getServiceExtension decodes the service's x-kubepose extension. A missing
+// extension yields the zero value.
+func getServiceExtension(service types.ServiceConfig) (serviceExtension, error) {
+	var ext serviceExtension
+	if _, err := service.Extensions.Get(ServiceExtensionKey, &ext); err != nil {
+		return ext, fmt.Errorf("invalid %s extension: %w", ServiceExtensionKey, err)
+	}
+	return ext, nil
+}
+
+// secretExtension holds the settings read from a top-level secret's
+// x-kubepose extension.
+type secretExtension struct {
+	// ExternalSecret sources the secret through the External Secrets
+	// Operator; see externalSecretExtension.
+	ExternalSecret any `mapstructure:"externalSecret"`
+}
+
+// getSecretExtension decodes the secret's x-kubepose extension. A missing
+// extension yields the zero value.
+func getSecretExtension(secret types.SecretConfig) (secretExtension, error) {
+	var ext secretExtension
+	if _, err := secret.Extensions.Get(ServiceExtensionKey, &ext); err != nil {
+		return ext, fmt.Errorf("invalid %s extension: %w", ServiceExtensionKey, err)
+	}
+	return ext, nil
+}
+
+// decodeKubernetesValue converts a decoded YAML value into a Kubernetes API
+// type by round-tripping it through JSON, so the API type's json tags and
+// custom unmarshalers (e.g. resource.Quantity) apply. Unknown fields are
+// rejected to catch typos that Kubernetes would silently drop.
+func decodeKubernetesValue(value any, target any) error {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return err
+	}
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.DisallowUnknownFields()
+	return decoder.Decode(target)
+}
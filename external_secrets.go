@@ -0,0 +1,77 @@
+package kubepose
+
+import (
+	"fmt"
+
+	"github.com/compose-spec/compose-go/v2/types"
+	"github.com/middle-management/kubepose/internal/externalsecrets"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// externalSecretExtension is the x-kubepose.externalSecret extension of a
+// top-level compose secret: where the External Secrets Operator fetches the
+// value from.
+type externalSecretExtension struct {
+	SecretStoreRef  externalsecrets.SecretStoreRef  `json:"secretStoreRef"`
+	RemoteRef       externalsecrets.RemoteReference `json:"remoteRef"`
+	RefreshInterval *metav1.Duration                `json:"refreshInterval,omitempty"`
+}
+
+// getExternalSecretExtension decodes and validates x-kubepose.externalSecret,
+// returning nil when it is unset.
+func getExternalSecretExtension(secret types.SecretConfig) (*externalSecretExtension, error) {
+	ext, err := getSecretExtension(secret)
+	if err != nil || ext.ExternalSecret == nil {
+		return nil, err
+	}
+	var es externalSecretExtension
+	if err := decodeKubernetesValue(ext.ExternalSecret, &es); err != nil {
+		return nil, fmt.Errorf("invalid %s.externalSecret: %w", ServiceExtensionKey, err)
+	}
+	if es.SecretStoreRef.Name == "" {
+		return nil, fmt.Errorf("%s.externalSecret requires secretStoreRef.name", ServiceExtensionKey)
+	}
+	switch es.SecretStoreRef.Kind {
+	case "", "SecretStore", "ClusterSecretStore":
+	default:
+		return nil, fmt.Errorf("%s.externalSecret secretStoreRef.kind %q: expected SecretStore or ClusterSecretStore", ServiceExtensionKey, es.SecretStoreRef.Kind)
+	}
+	if es.RemoteRef.Key == "" {
+		return nil, fmt.Errorf("%s.externalSecret requires remoteRef.key", ServiceExtensionKey)
+	}
+	if es.RefreshInterval != nil && es.RefreshInterval.Duration < 0 {
+		return nil, fmt.Errorf("%s.externalSecret refreshInterval %s must not be negative", ServiceExtensionKey, es.RefreshInterval.Duration)
+	}
+	return &es, nil
+}
+
+// createExternalSecret emits an ExternalSecret that makes the operator create
+// and refresh the Secret targetName, holding the remote value under the
+// compose secret's name. Unlike generated Secrets it is neither immutable nor
+// content-hashed: the operator updates it in place. It carries no
+// kubepose.secret label, since the operator copies its labels onto the
+// Secret, which prune must never treat as a superseded generated Secret.
+func (t Transformer) createExternalSecret(resources *Resources, project *types.Project, name, targetName string, secret types.SecretConfig, es *externalSecretExtension) {
+	resources.ExternalSecrets = append(resources.ExternalSecrets, &externalsecrets.ExternalSecret{
+		TypeMeta: metav1.TypeMeta{
+			APIVersion: externalsecrets.SchemeGroupVersion.String(),
+			Kind:       "ExternalSecret",
+		},
+		ObjectMeta: metav1.ObjectMeta{
+			Name: targetName,
+			Labels: mergeMaps(secret.Labels, t.Labels, map[string]string{
+				ProjectLabelKey: project.Name,
+			}),
+			Annotations: mergeMaps(t.Annotations),
+		},
+		Spec: externalsecrets.ExternalSecretSpec{
+			SecretStoreRef:  es.SecretStoreRef,
+			Target:          externalsecrets.ExternalSecretTarget{Name: targetName},
+			RefreshInterval: es.RefreshInterval,
+			Data: []externalsecrets.ExternalSecretData{{
+				SecretKey: name,
+				RemoteRef: es.RemoteRef,
+			}},
+		},
+	})
+}
@@ -0,0 +1,83 @@
+package kubepose
+
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/compose-spec/compose-go/v2/types"
+	"github.com/sirupsen/logrus"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/utils/ptr"
+)
+
+// validateFileReferences rejects config and secret modes Kubernetes cannot
+// store and owners that are not numeric IDs. Called from validateService.
+func validateFileReferences(service types.ServiceConfig) error {
+	check := func(kind string, ref types.FileReferenceConfig) error {
+		if ref.Mode != nil && (*ref.Mode < 0 || *ref.Mode > 0o777) {
+			return fmt.Errorf("%s %q: mode %#o: expected permission bits between 0 and 0777", kind, ref.Source, *ref.Mode)
+		}
+		for _, id := range []struct{ field, value string }{{"uid", ref.UID}, {"gid", ref.GID}} {
+			if id.value == "" {
+				continue
+			}
+			if _, err := strconv.ParseInt(id.value, 10, 64); err != nil {
+				return fmt.Errorf("%s %q: %s %q: only numeric IDs are supported on Kubernetes", kind, ref.Source, id.field, id.value)
+			}
+		}
+		return nil
+	}
+	for _, ref := range service.Configs {
+		if err := check("config", types.FileReferenceConfig(ref)); err != nil {
+			return err
+		}
+	}
+	for _, ref := range service.Secrets {
+		if err := check("secret", types.FileReferenceConfig(ref)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// fileReferenceVolumeName names the pod volume for a config or secret.
+// References with a mode get their own volume, since the mode is a property
+// of the volume and grouped services may mount the same source with
+// different modes.
+func fileReferenceVolumeName(ref types.FileReferenceConfig) string {
+	if ref.Mode == nil {
+		return ref.Source
+	}
+	return fmt.Sprintf("%s-%o", ref.Source, *ref.Mode)
+}
+
+func fileReferenceMode(ref types.FileReferenceConfig) *int32 {
+	if ref.Mode == nil {
+		return nil
+	}
+	return ptr.To(int32(*ref.Mode))
+}
+
+// warnFileOwnership reports uid and gid settings the mounted file will not
+// have. Kubernetes projects configs and secrets owned by root and, when the
+// pod has an fsGroup, group-owned by it; there is no per-file owner.
+func warnFileOwnership(spec *corev1.PodSpec, service types.ServiceConfig, kind string, ref types.FileReferenceConfig) {
+	if ref.UID != "" && ref.UID != "0" {
+		logrus.Warnf("service %q: %s %q: uid %s cannot be honoured, Kubernetes mounts the file owned by root; use a gid matching the pod fsGroup and a group-readable mode instead", service.Name, kind, ref.Source, ref.UID)
+	}
+	if ref.GID == "" {
+		return
+	}
+	// Validated in validateService; parse errors cannot occur here.
+	gid, _ := strconv.ParseInt(ref.GID, 10, 64)
+	var fsGroup *int64
+	if spec.SecurityContext != nil {
+		fsGroup = spec.SecurityContext.FSGroup
+	}
+	switch {
+	case fsGroup == nil && gid != 0:
+		logrus.Warnf("service %q: %s %q: gid %s cannot be honoured, the pod has no fsGroup (set the group in user:, e.g. user: \"1000:%s\")", service.Name, kind, ref.Source, ref.GID, ref.GID)
+	case fsGroup != nil && *fsGroup != gid:
+		logrus.Warnf("service %q: %s %q: gid %s cannot be honoured, the file is group-owned by the pod fsGroup %d", service.Name, kind, ref.Source, ref.GID, *fsGroup)
+	}
+}
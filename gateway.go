@@ -0,0 +1,166 @@
+package kubepose
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/compose-spec/compose-go/v2/types"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/utils/ptr"
+	gatewayv1 "sigs.k8s.io/gateway-api/apis/v1"
+	gatewayv1alpha2 "sigs.k8s.io/gateway-api/apis/v1alpha2"
+)
+
+// Values for Transformer.IngressAPI.
+const (
+	IngressAPIIngress = "ingress"
+	IngressAPIGateway = "gateway"
+)
+
+// usesGatewayAPI reports whether an exposed service is routed through Gateway
+// API instead of an Ingress: either the service names its own parent Gateway
+// or the transformer defaults to Gateway API.
+func (t Transformer) usesGatewayAPI(service types.ServiceConfig) bool {
+	if _, ok := service.Annotations[ServiceExposeGatewayAnnotationKey]; ok {
+		return true
+	}
+	return t.IngressAPI == IngressAPIGateway
+}
+
+// getGatewayParentRef returns the parent Gateway a service's routes attach
+// to, preferring the service annotation over the transformer-wide default.
+func (t Transformer) getGatewayParentRef(service types.ServiceConfig) (gatewayv1.ParentReference, error) {
+	parent := t.Gateway
+	if value, ok := service.Annotations[ServiceExposeGatewayAnnotationKey]; ok {
+		parent = value
+	}
+	if parent == "" {
+		return gatewayv1.ParentReference{}, fmt.Errorf("%s requires a parent Gateway: set %s or the --gateway flag", ServiceExposeAnnotationKey, ServiceExposeGatewayAnnotationKey)
+	}
+
+	namespace, name, hasNamespace := strings.Cut(parent, "/")
+	if !hasNamespace {
+		namespace, name = "", parent
+	}
+	if name == "" || strings.Contains(name, "/") || (hasNamespace && namespace == "") {
+		return gatewayv1.ParentReference{}, fmt.Errorf("invalid parent Gateway %q: expected \"name\" or \"namespace/name\"", parent)
+	}
+
+	ref := gatewayv1.ParentReference{Name: gatewayv1.ObjectName(name)}
+	if namespace != "" {
+		ref.Namespace = ptr.To(gatewayv1.Namespace(namespace))
+	}
+	return ref, nil
+}
+
+// validateGateway rejects Gateway API settings that could not produce a
+// route. Called from Convert, since the default parent lives on the
+// Transformer rather than the service.
+func (t Transformer) validateGateway(service types.ServiceConfig) error {
+	if _, ok := service.Annotations[ServiceExposeAnnotationKey]; !ok {
+		if _, ok := service.Annotations[ServiceExposeGatewayAnnotationKey]; ok {
+			return fmt.Errorf("%s has no effect without %s", ServiceExposeGatewayAnnotationKey, ServiceExposeAnnotationKey)
+		}
+		return nil
+	}
+	if !t.usesGatewayAPI(service) {
+		return nil
+	}
+	_, err := t.getGatewayParentRef(service)
+	return err
+}
+
+// createGatewayRoute emits the Gateway API equivalent of createIngress for the
+// same hosts and port. The route kind follows the port's app_protocol: grpc
+// becomes a GRPCRoute, HTTP-family protocols (or none) an HTTPRoute, and
+// anything else a TCPRoute, which routes by listener rather than hostname.
+func (t Transformer) createGatewayRoute(resources *Resources, service types.ServiceConfig) error {
+	parentRef, err := t.getGatewayParentRef(service)
+	if err != nil {
+		return err
+	}
+
+	hosts := getExposeHosts(service)
+	if len(hosts) == 0 {
+		return nil
+	}
+
+	servicePort := getExposePort(service)
+	if servicePort.Number == 0 {
+		// A portless service has nothing a route could forward to.
+		return nil
+	}
+
+	var hostnames []gatewayv1.Hostname
+	for _, host := range hosts {
+		hostnames = append(hostnames, gatewayv1.Hostname(host))
+	}
+
+	commonSpec := gatewayv1.CommonRouteSpec{
+		ParentRefs: []gatewayv1.ParentReference{parentRef},
+	}
+	backendRef := gatewayv1.BackendRef{
+		BackendObjectReference: gatewayv1.BackendObjectReference{
+			Name: gatewayv1.ObjectName(getServiceName(service)),
+			Port: ptr.To(gatewayv1.PortNumber(servicePort.Number)),
+		},
+	}
+	objectMeta := metav1.ObjectMeta{
+		Name:        service.Name,
+		Annotations: mergeMaps(service.Annotations, t.Annotations),
+		Labels:      mergeMaps(service.Labels, t.Labels),
+	}
+
+	switch strings.ToLower(servicePort.AppProtocol) {
+	case "grpc":
+		resources.GRPCRoutes = append(resources.GRPCRoutes, &gatewayv1.GRPCRoute{
+			TypeMeta: metav1.TypeMeta{
+				APIVersion: gatewayv1.GroupVersion.String(),
+				Kind:       "GRPCRoute",
+			},
+			ObjectMeta: objectMeta,
+			Spec: gatewayv1.GRPCRouteSpec{
+				CommonRouteSpec: commonSpec,
+				Hostnames:       hostnames,
+				Rules: []gatewayv1.GRPCRouteRule{{
+					BackendRefs: []gatewayv1.GRPCBackendRef{{BackendRef: backendRef}},
+				}},
+			},
+		})
+	case "", "http", "https", "http2", "h2c", "ws", "wss",
+		"kubernetes.io/h2c", "kubernetes.io/ws", "kubernetes.io/wss":
+		// No matches means the Gateway API default: a "/" path prefix, the
+		// same catch-all the Ingress rule uses.
+		resources.HTTPRoutes = append(resources.HTTPRoutes, &gatewayv1.HTTPRoute{
+			TypeMeta: metav1.TypeMeta{
+				APIVersion: gatewayv1.GroupVersion.String(),
+				Kind:       "HTTPRoute",
+			},
+			ObjectMeta: objectMeta,
+			Spec: gatewayv1.HTTPRouteSpec{
+				CommonRouteSpec: commonSpec,
+				Hostnames:       hostnames,
+				Rules: []gatewayv1.HTTPRouteRule{{
+					BackendRefs: []gatewayv1.HTTPBackendRef{{BackendRef: backendRef}},
+				}},
+			},
+		})
+	default:
+		// TCPRoute is served as v1alpha2 by every Gateway API release that
+		// ships it, so it is the most portable version to emit.
+		resources.TCPRoutes = append(resources.TCPRoutes, &gatewayv1alpha2.TCPRoute{
+			TypeMeta: metav1.TypeMeta{
+				APIVersion: gatewayv1alpha2.GroupVersion.String(),
+				Kind:       "TCPRoute",
+			},
+			ObjectMeta: objectMeta,
+			Spec: gatewayv1alpha2.TCPRouteSpec{
+				CommonRouteSpec: commonSpec,
+				Rules: []gatewayv1alpha2.TCPRouteRule{{
+					BackendRefs: []gatewayv1.BackendRef{backendRef},
+				}},
+			},
+		})
+	}
+	return nil
+}
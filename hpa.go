@@ -1,6 +1,7 @@
 package kubepose
 
 import (
+	"encoding/json"
 	"fmt"
 	"strconv"
 
@@ -22,7 +23,8 @@ func hasHorizontalPodAutoscaler(service types.ServiceConfig) bool {
 }
 
 // createHorizontalPodAutoscaler emits an autoscaling/v2 HPA targeting the
-// service's Deployment, scaling on average CPU utilization.
+// service's Deployment, scaling on average CPU utilization and any Pods,
+// Object or External metrics, with the configured scaling behavior.
 //
 // The Deployment's spec.replicas must be left unset when an HPA manages it
 // (see createDeployment): a pinned value would reset the HPA's chosen scale
@@ -54,6 +56,22 @@ func (t Transformer) createHorizontalPodAutoscaler(resources *Resources, service
 	if value, ok := service.Annotations[HpaCpuAnnotationKey]; ok {
 		cpuUtilization, _ = strconv.Atoi(value)
 	}
+	hpaExt, _ := getHpaExtension(service)
+
+	var metrics []autoscalingv2.MetricSpec
+	if hpaUsesCpu(service, hpaExt) {
+		metrics = append(metrics, autoscalingv2.MetricSpec{
+			Type: autoscalingv2.ResourceMetricSourceType,
+			Resource: &autoscalingv2.ResourceMetricSource{
+				Name: "cpu",
+				Target: autoscalingv2.MetricTarget{
+					Type:               autoscalingv2.UtilizationMetricType,
+					AverageUtilization: ptr.To(int32(cpuUtilization)),
+				},
+			},
+		})
+	}
+	metrics = append(metrics, hpaExt.Metrics...)
 
 	hpa := &autoscalingv2.HorizontalPodAutoscaler{
 		TypeMeta: metav1.TypeMeta{
@@ -73,24 +91,23 @@ func (t Transformer) createHorizontalPodAutoscaler(resources *Resources, service
 			},
 			MinReplicas: ptr.To(int32(minReplicas)),
 			MaxReplicas: int32(maxReplicas),
-			Metrics: []autoscalingv2.MetricSpec{
-				{
-					Type: autoscalingv2.ResourceMetricSourceType,
-					Resource: &autoscalingv2.ResourceMetricSource{
-						Name: "cpu",
-						Target: autoscalingv2.MetricTarget{
-							Type:               autoscalingv2.UtilizationMetricType,
-							AverageUtilization: ptr.To(int32(cpuUtilization)),
-						},
-					},
-				},
-			},
+			Metrics:     metrics,
+			Behavior:    hpaExt.Behavior,
 		},
 	}
 	resources.HorizontalPodAutoscalers = append(resources.HorizontalPodAutoscalers, hpa)
 	return hpa
 }
 
+// hpaUsesCpu reports whether the HPA targets CPU utilization: always without
+// custom metrics, otherwise only when kubepose.hpa.cpu asks for it.
+func hpaUsesCpu(service types.ServiceConfig, hpa hpaExtension) bool {
+	if _, ok := service.Annotations[HpaCpuAnnotationKey]; ok {
+		return true
+	}
+	return len(hpa.Metrics) == 0
+}
+
 // validateHpaAnnotations rejects HPA annotation combinations the converter
 // cannot faithfully translate. Called from validateService.
 func validateHpaAnnotations(service types.ServiceConfig) error {
@@ -98,6 +115,11 @@ func validateHpaAnnotations(service types.ServiceConfig) error {
 	minValue, hasMin := service.Annotations[HpaMinReplicasAnnotationKey]
 	cpuValue, hasCpu := service.Annotations[HpaCpuAnnotationKey]
 
+	hpa, err := getHpaExtension(service)
+	if err != nil {
+		return err
+	}
+
 	if !hasMax {
 		if hasMin {
 			return fmt.Errorf("%s has no effect without %s", HpaMinReplicasAnnotationKey, HpaMaxReplicasAnnotationKey)
@@ -105,8 +127,19 @@ func validateHpaAnnotations(service types.ServiceConfig) error {
 		if hasCpu {
 			return fmt.Errorf("%s has no effect without %s", HpaCpuAnnotationKey, HpaMaxReplicasAnnotationKey)
 		}
+		for _, key := range hpaBehaviorAndMetricsAnnotationKeys {
+			if _, ok := service.Annotations[key]; ok {
+				return fmt.Errorf("%s has no effect without %s", key, HpaMaxReplicasAnnotationKey)
+			}
+		}
+		if hpa.Behavior != nil || len(hpa.Metrics) > 0 {
+			return fmt.Errorf("%s.hpa has no effect without %s", ServiceExtensionKey, HpaMaxReplicasAnnotationKey)
+		}
 		return nil
 	}
+	if err := validateHpaExtension(hpa); err != nil {
+		return err
+	}
 
 	// ParseInt with bitSize 32 so out-of-range values fail here instead of
 	// silently truncating in createHorizontalPodAutoscaler's int32 casts.
@@ -147,6 +180,10 @@ func validateHpaAnnotations(service types.ServiceConfig) error {
 
 	// The CPU-utilization metric is a percentage of the container's CPU
 	// request; without a reservation the HPA can never compute utilization.
+	// With custom metrics, CPU is only a target when kubepose.hpa.cpu is set.
+	if !hpaUsesCpu(service, hpa) {
+		return nil
+	}
 	if service.Deploy == nil ||
 		service.Deploy.Resources.Reservations == nil ||
 		service.Deploy.Resources.Reservations.NanoCPUs.Value() <= 0 {
@@ -155,3 +192,170 @@ func validateHpaAnnotations(service types.ServiceConfig) error {
 
 	return nil
 }
+
+// hpaExtension is the x-kubepose.hpa service extension: replica bounds and
+// CPU target, aliasing their kubepose.hpa.* annotations, and HPA behavior and
+// additional metrics in their autoscaling/v2 shape.
+type hpaExtension struct {
+	MaxReplicas *int32 `json:"maxReplicas,omitempty"`
+	MinReplicas *int32 `json:"minReplicas,omitempty"`
+	CPU         *int32 `json:"cpu,omitempty"`
+
+	Behavior *autoscalingv2.HorizontalPodAutoscalerBehavior `json:"behavior,omitempty"`
+	Metrics  []autoscalingv2.MetricSpec                     `json:"metrics,omitempty"`
+}
+
+// hpaBehaviorAndMetricsAnnotationKeys are the annotations getHpaExtension
+// folds into the extension.
+var hpaBehaviorAndMetricsAnnotationKeys = []string{
+	HpaScaleUpStabilizationWindowAnnotationKey,
+	HpaScaleDownStabilizationWindowAnnotationKey,
+	HpaScaleUpPoliciesAnnotationKey,
+	HpaScaleDownPoliciesAnnotationKey,
+	HpaMetricsAnnotationKey,
+}
+
+// getHpaExtension returns the HPA behavior and extra metrics from
+// x-kubepose.hpa, with the per-direction kubepose.hpa.* annotations applied on
+// top. Metrics may come from the extension or the annotation, not both.
+func getHpaExtension(service types.ServiceConfig) (hpaExtension, error) {
+	var hpa hpaExtension
+	ext, err := getServiceExtension(service)
+	if err != nil {
+		return hpa, err
+	}
+	if ext.HPA != nil {
+		if err := decodeKubernetesValue(ext.HPA, &hpa); err != nil {
+			return hpa, fmt.Errorf("invalid %s.hpa: %w", ServiceExtensionKey, err)
+		}
+	}
+
+	if value, ok := service.Annotations[HpaMetricsAnnotationKey]; ok {
+		if len(hpa.Metrics) > 0 {
+			return hpa, fmt.Errorf("%s cannot be combined with %s.hpa.metrics", HpaMetricsAnnotationKey, ServiceExtensionKey)
+		}
+		if err := decodeKubernetesValue(json.RawMessage(value), &hpa.Metrics); err != nil {
+			return hpa, fmt.Errorf("%s: invalid JSON metric list: %w", HpaMetricsAnnotationKey, err)
+		}
+	}
+
+	if hpa.Behavior == nil {
+		hpa.Behavior = &autoscalingv2.HorizontalPodAutoscalerBehavior{}
+	}
+	if err := applyScalingRulesAnnotations(&hpa.Behavior.ScaleUp, service, HpaScaleUpStabilizationWindowAnnotationKey, HpaScaleUpPoliciesAnnotationKey); err != nil {
+		return hpa, err
+	}
+	if err := applyScalingRulesAnnotations(&hpa.Behavior.ScaleDown, service, HpaScaleDownStabilizationWindowAnnotationKey, HpaScaleDownPoliciesAnnotationKey); err != nil {
+		return hpa, err
+	}
+	if hpa.Behavior.ScaleUp == nil && hpa.Behavior.ScaleDown == nil {
+		hpa.Behavior = nil
+	}
+	return hpa, nil
+}
+
+// applyScalingRulesAnnotations sets one scaling direction's stabilization
+// window and policies from its annotations, overriding the extension.
+func applyScalingRulesAnnotations(rules **autoscalingv2.HPAScalingRules, service types.ServiceConfig, windowKey, policiesKey string) error {
+	window, hasWindow := service.Annotations[windowKey]
+	policies, hasPolicies := service.Annotations[policiesKey]
+	if !hasWindow && !hasPolicies {
+		return nil
+	}
+	if *rules == nil {
+		*rules = &autoscalingv2.HPAScalingRules{}
+	}
+	if hasWindow {
+		seconds, err := strconv.ParseInt(window, 10, 32)
+		if err != nil {
+			return fmt.Errorf("%s must be an integer number of seconds, got %q", windowKey, window)
+		}
+		(*rules).StabilizationWindowSeconds = ptr.To(int32(seconds))
+	}
+	if hasPolicies {
+		(*rules).Policies = nil
+		if err := decodeKubernetesValue(json.RawMessage(policies), &(*rules).Policies); err != nil {
+			return fmt.Errorf("%s: invalid JSON policy list: %w", policiesKey, err)
+		}
+	}
+	return nil
+}
+
+// validateHpaExtension rejects behavior and metrics the HPA controller would
+// refuse. Resource metrics are left to kubepose.hpa.cpu, which also checks
+// the CPU reservation they depend on.
+func validateHpaExtension(hpa hpaExtension) error {
+	if hpa.Behavior != nil {
+		for direction, rules := range map[string]*autoscalingv2.HPAScalingRules{
+			"scaleUp":   hpa.Behavior.ScaleUp,
+			"scaleDown": hpa.Behavior.ScaleDown,
+		} {
+			if rules == nil {
+				continue
+			}
+			if w := rules.StabilizationWindowSeconds; w != nil && (*w < 0 || *w > 3600) {
+				return fmt.Errorf("hpa %s stabilizationWindowSeconds must be between 0 and 3600, got %d", direction, *w)
+			}
+			if p := rules.SelectPolicy; p != nil && *p != autoscalingv2.MaxChangePolicySelect && *p != autoscalingv2.MinChangePolicySelect && *p != autoscalingv2.DisabledPolicySelect {
+				return fmt.Errorf("hpa %s selectPolicy must be Max, Min or Disabled, got %q", direction, *p)
+			}
+			for _, policy := range rules.Policies {
+				if policy.Type != autoscalingv2.PodsScalingPolicy && policy.Type != autoscalingv2.PercentScalingPolicy {
+					return fmt.Errorf("hpa %s policy type must be Pods or Percent, got %q", direction, policy.Type)
+				}
+				if policy.Value < 1 {
+					return fmt.Errorf("hpa %s %s policy value must be positive, got %d", direction, policy.Type, policy.Value)
+				}
+				if policy.PeriodSeconds < 1 || policy.PeriodSeconds > 1800 {
+					return fmt.Errorf("hpa %s %s policy periodSeconds must be between 1 and 1800, got %d", direction, policy.Type, policy.PeriodSeconds)
+				}
+			}
+		}
+	}
+
+	for i, metric := range hpa.Metrics {
+		var target autoscalingv2.MetricTarget
+		var name string
+		switch metric.Type {
+		case autoscalingv2.PodsMetricSourceType:
+			if metric.Pods == nil {
+				return fmt.Errorf("hpa metric %d: type Pods requires pods", i)
+			}
+			name, target = metric.Pods.Metric.Name, metric.Pods.Target
+			if target.Type != autoscalingv2.AverageValueMetricType {
+				return fmt.Errorf("hpa metric %d (%s): Pods metrics require an AverageValue target", i, name)
+			}
+		case autoscalingv2.ObjectMetricSourceType:
+			if metric.Object == nil {
+				return fmt.Errorf("hpa metric %d: type Object requires object", i)
+			}
+			name, target = metric.Object.Metric.Name, metric.Object.Target
+			if metric.Object.DescribedObject.Kind == "" || metric.Object.DescribedObject.Name == "" {
+				return fmt.Errorf("hpa metric %d (%s): Object metrics require describedObject kind and name", i, name)
+			}
+		case autoscalingv2.ExternalMetricSourceType:
+			if metric.External == nil {
+				return fmt.Errorf("hpa metric %d: type External requires external", i)
+			}
+			name, target = metric.External.Metric.Name, metric.External.Target
+		default:
+			return fmt.Errorf("hpa metric %d: type must be Pods, Object or External, got %q (use %s for CPU)", i, metric.Type, HpaCpuAnnotationKey)
+		}
+		if name == "" {
+			return fmt.Errorf("hpa metric %d: metric name is required", i)
+		}
+		switch target.Type {
+		case autoscalingv2.ValueMetricType:
+			if target.Value == nil || target.Value.Sign() <= 0 {
+				return fmt.Errorf("hpa metric %d (%s): a Value target requires a positive value", i, name)
+			}
+		case autoscalingv2.AverageValueMetricType:
+			if target.AverageValue == nil || target.AverageValue.Sign() <= 0 {
+				return fmt.Errorf("hpa metric %d (%s): an AverageValue target requires a positive averageValue", i, name)
+			}
+		default:
+			return fmt.Errorf("hpa metric %d (%s): target type must be Value or AverageValue, got %q", i, name, target.Type)
+		}
+	}
+	return nil
+}
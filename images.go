@@ -0,0 +1,101 @@
+package kubepose
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/compose-spec/compose-go/v2/types"
+	"github.com/distribution/reference"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/util/validation"
+)
+
+// validateRegistryMirrors rejects mirror prefixes that could never match a
+// normalized image reference.
+func (t Transformer) validateRegistryMirrors() error {
+	for from, to := range t.RegistryMirrors {
+		if from == "" || to == "" || strings.HasSuffix(from, "/") || strings.HasSuffix(to, "/") {
+			return fmt.Errorf("registry mirror %q=%q: expected from=to with registry or repository prefixes such as docker.io=registry.local/dockerhub", from, to)
+		}
+	}
+	return nil
+}
+
+// rewriteImage points an image reference at the mirror with the longest
+// matching prefix. Prefixes match the normalized name on path boundaries, so
+// "docker.io" matches "nginx" (docker.io/library/nginx) but "ghcr.io/org"
+// does not match "ghcr.io/organization/app". The tag and digest are kept as
+// written; references that fail to parse are left for the cluster to reject.
+func (t Transformer) rewriteImage(image string) string {
+	if len(t.RegistryMirrors) == 0 || image == "" {
+		return image
+	}
+	named, err := reference.ParseNormalizedNamed(image)
+	if err != nil {
+		return image
+	}
+	name := named.Name()
+
+	var match string
+	for from := range t.RegistryMirrors {
+		if (name == from || strings.HasPrefix(name, from+"/")) && len(from) > len(match) {
+			match = from
+		}
+	}
+	if match == "" {
+		return image
+	}
+
+	rewritten := t.RegistryMirrors[match] + strings.TrimPrefix(name, match)
+	if tagged, ok := named.(reference.Tagged); ok {
+		rewritten += ":" + tagged.Tag()
+	}
+	if digested, ok := named.(reference.Digested); ok {
+		rewritten += "@" + digested.Digest().String()
+	}
+	return rewritten
+}
+
+// getImagePullSecrets returns the pull secrets from the transformer default
+// and kubepose.pod.imagePullSecrets, in that order and without duplicates.
+func (t Transformer) getImagePullSecrets(service types.ServiceConfig) []corev1.LocalObjectReference {
+	var refs []corev1.LocalObjectReference
+	for _, name := range append(slices.Clone(t.ImagePullSecrets), splitImagePullSecrets(service)...) {
+		if !slices.Contains(refs, corev1.LocalObjectReference{Name: name}) {
+			refs = append(refs, corev1.LocalObjectReference{Name: name})
+		}
+	}
+	return refs
+}
+
+func splitImagePullSecrets(service types.ServiceConfig) []string {
+	var names []string
+	for _, name := range strings.Split(service.Annotations[ImagePullSecretsAnnotationKey], ",") {
+		if name = strings.TrimSpace(name); name != "" {
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+// validateImagePullSecrets checks the annotation names Secrets Kubernetes
+// could hold. Called from validateService.
+func validateImagePullSecrets(service types.ServiceConfig) error {
+	for _, name := range splitImagePullSecrets(service) {
+		if errs := validation.IsDNS1123Subdomain(name); len(errs) > 0 {
+			return fmt.Errorf("%s %q: %s", ImagePullSecretsAnnotationKey, name, strings.Join(errs, "; "))
+		}
+	}
+	return nil
+}
+
+// mergePodImagePullSecrets adds a grouped service's pull secrets to the
+// shared pod; any member's images may need them.
+func (t Transformer) mergePodImagePullSecrets(podSpec *corev1.PodSpec, service types.ServiceConfig) {
+	for _, ref := range t.getImagePullSecrets(service) {
+		if !slices.Contains(podSpec.ImagePullSecrets, ref) {
+			podSpec.ImagePullSecrets = append(podSpec.ImagePullSecrets, ref)
+		}
+	}
+}
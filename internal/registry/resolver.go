@@ -0,0 +1,347 @@
+// Package registry resolves image tags to manifest digests through the OCI
+// distribution API, with an optional on-disk cache so conversions can be
+// repeated offline.
+package registry
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/distribution/reference"
+)
+
+// manifestMediaTypes are accepted when resolving a tag. Index and list types
+// come first so multi-platform images pin to the index, as docker pull does.
+var manifestMediaTypes = []string{
+	"application/vnd.oci.image.index.v1+json",
+	"application/vnd.docker.distribution.manifest.list.v2+json",
+	"application/vnd.oci.image.manifest.v1+json",
+	"application/vnd.docker.distribution.manifest.v2+json",
+}
+
+// Resolver resolves image references to digests. The zero value resolves
+// over the network with http.DefaultClient and no cache.
+type Resolver struct {
+	// Client performs registry requests; nil means http.DefaultClient.
+	Client *http.Client
+	// CacheFile, when set, is a JSON object of image reference to digest
+	// read by Load and written by Save.
+	CacheFile string
+	// Offline resolves from the cache only.
+	Offline bool
+	// DockerConfig is the path of a docker config.json whose "auths"
+	// entries supply registry credentials; empty means
+	// $DOCKER_CONFIG/config.json or ~/.docker/config.json.
+	DockerConfig string
+
+	mu     sync.Mutex
+	cache  map[string]string
+	dirty  bool
+	tokens map[string]string
+}
+
+// Load reads the cache file. A missing file is an empty cache.
+func (r *Resolver) Load() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.cache = map[string]string{}
+	if r.CacheFile == "" {
+		return nil
+	}
+	data, err := os.ReadFile(r.CacheFile)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("reading digest cache: %w", err)
+	}
+	if err := json.Unmarshal(data, &r.cache); err != nil {
+		return fmt.Errorf("reading digest cache %s: %w", r.CacheFile, err)
+	}
+	return nil
+}
+
+// Save writes the cache file if any digest was resolved since Load.
+func (r *Resolver) Save() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.CacheFile == "" || !r.dirty {
+		return nil
+	}
+	data, err := json.MarshalIndent(r.cache, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(r.CacheFile, append(data, '\n'), 0o644); err != nil {
+		return fmt.Errorf("writing digest cache: %w", err)
+	}
+	r.dirty = false
+	return nil
+}
+
+// ResolveDigest returns the manifest digest ("sha256:...") the image's tag
+// currently points to. References without a tag resolve "latest", and
+// references already pinned to a digest return it without a lookup.
+func (r *Resolver) ResolveDigest(image string) (string, error) {
+	named, err := reference.ParseNormalizedNamed(image)
+	if err != nil {
+		return "", fmt.Errorf("image %q: %w", image, err)
+	}
+	if canonical, ok := named.(reference.Canonical); ok {
+		return canonical.Digest().String(), nil
+	}
+	tagged, ok := reference.TagNameOnly(named).(reference.NamedTagged)
+	if !ok {
+		return "", fmt.Errorf("image %q: no tag to resolve", image)
+	}
+	key := tagged.String()
+
+	r.mu.Lock()
+	if r.cache == nil {
+		r.cache = map[string]string{}
+	}
+	digest, ok := r.cache[key]
+	r.mu.Unlock()
+	if ok {
+		return digest, nil
+	}
+	if r.Offline {
+		return "", fmt.Errorf("image %q: no cached digest for %s (offline)", image, key)
+	}
+
+	digest, err = r.fetchDigest(tagged)
+	if err != nil {
+		return "", fmt.Errorf("image %q: %w", image, err)
+	}
+
+	r.mu.Lock()
+	r.cache[key] = digest
+	r.dirty = true
+	r.mu.Unlock()
+	return digest, nil
+}
+
+func (r *Resolver) client() *http.Client {
+	if r.Client != nil {
+		return r.Client
+	}
+	return http.DefaultClient
+}
+
+func (r *Resolver) fetchDigest(named reference.NamedTagged) (string, error) {
+	host := reference.Domain(named)
+	if host == "docker.io" {
+		host = "registry-1.docker.io"
+	}
+	repository := reference.Path(named)
+	manifestURL := fmt.Sprintf("https://%s/v2/%s/manifests/%s", host, repository, named.Tag())
+
+	for _, method := range []string{http.MethodHead, http.MethodGet} {
+		resp, err := r.do(method, manifestURL, host, repository)
+		if err != nil {
+			return "", err
+		}
+		body, err := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		if err != nil {
+			return "", err
+		}
+		switch {
+		case resp.StatusCode == http.StatusNotFound:
+			return "", fmt.Errorf("tag %s not found in %s/%s", named.Tag(), host, repository)
+		case resp.StatusCode == http.StatusUnauthorized || resp.StatusCode == http.StatusForbidden:
+			return "", fmt.Errorf("access to %s/%s denied (%s); add credentials with docker login", host, repository, resp.Status)
+		case resp.StatusCode != http.StatusOK:
+			return "", fmt.Errorf("resolving %s: unexpected status %s", manifestURL, resp.Status)
+		}
+		if digest := resp.Header.Get("Docker-Content-Digest"); digest != "" {
+			return digest, nil
+		}
+		// Some registries only send the digest header on GET; hash the
+		// manifest ourselves as a last resort.
+		if method == http.MethodGet {
+			return fmt.Sprintf("sha256:%x", sha256.Sum256(body)), nil
+		}
+	}
+	return "", fmt.Errorf("resolving %s: the registry sent no manifest digest", manifestURL)
+}
+
+// do sends a registry request, answering a bearer challenge once with a
+// token from the registry's auth service.
+func (r *Resolver) do(method, rawURL, host, repository string) (*http.Response, error) {
+	newRequest := func() (*http.Request, error) {
+		req, err := http.NewRequest(method, rawURL, nil)
+		if err != nil {
+			return nil, err
+		}
+		req.Header.Set("Accept", strings.Join(manifestMediaTypes, ", "))
+		return req, nil
+	}
+
+	req, err := newRequest()
+	if err != nil {
+		return nil, err
+	}
+	r.authorize(req, host, repository)
+	resp, err := r.client().Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusUnauthorized {
+		return resp, nil
+	}
+	challenge := resp.Header.Get("WWW-Authenticate")
+	resp.Body.Close()
+
+	scheme, params := parseChallenge(challenge)
+	req, err = newRequest()
+	if err != nil {
+		return nil, err
+	}
+	switch scheme {
+	case "bearer":
+		token, err := r.fetchToken(params, host, repository)
+		if err != nil {
+			return nil, err
+		}
+		r.mu.Lock()
+		if r.tokens == nil {
+			r.tokens = map[string]string{}
+		}
+		r.tokens[host+"/"+repository] = token
+		r.mu.Unlock()
+		req.Header.Set("Authorization", "Bearer "+token)
+	case "basic":
+		username, password, ok := r.credentials(host)
+		if !ok {
+			return nil, fmt.Errorf("%s requires credentials; add them with docker login", host)
+		}
+		req.SetBasicAuth(username, password)
+	default:
+		return nil, fmt.Errorf("%s: unsupported authentication challenge %q", host, challenge)
+	}
+	return r.client().Do(req)
+}
+
+// authorize reuses a token fetched for the same repository earlier in the
+// run, saving a challenge round trip per image.
+func (r *Resolver) authorize(req *http.Request, host, repository string) {
+	r.mu.Lock()
+	token, ok := r.tokens[host+"/"+repository]
+	r.mu.Unlock()
+	if ok {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+}
+
+func (r *Resolver) fetchToken(params map[string]string, host, repository string) (string, error) {
+	realm, err := url.Parse(params["realm"])
+	if err != nil || realm.Scheme == "" {
+		return "", fmt.Errorf("%s: invalid bearer realm %q", host, params["realm"])
+	}
+	query := realm.Query()
+	if service := params["service"]; service != "" {
+		query.Set("service", service)
+	}
+	query.Set("scope", fmt.Sprintf("repository:%s:pull", repository))
+	realm.RawQuery = query.Encode()
+
+	req, err := http.NewRequest(http.MethodGet, realm.String(), nil)
+	if err != nil {
+		return "", err
+	}
+	if username, password, ok := r.credentials(host); ok {
+		req.SetBasicAuth(username, password)
+	}
+	resp, err := r.client().Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("%s: token request failed: %s", host, resp.Status)
+	}
+	var body struct {
+		Token       string `json:"token"`
+		AccessToken string `json:"access_token"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return "", fmt.Errorf("%s: decoding token: %w", host, err)
+	}
+	if body.Token != "" {
+		return body.Token, nil
+	}
+	return body.AccessToken, nil
+}
+
+// parseChallenge splits a WWW-Authenticate header such as
+// `Bearer realm="https://auth.docker.io/token",service="registry.docker.io"`.
+func parseChallenge(header string) (string, map[string]string) {
+	scheme, rest, _ := strings.Cut(header, " ")
+	params := map[string]string{}
+	for _, part := range strings.Split(rest, ",") {
+		key, value, ok := strings.Cut(strings.TrimSpace(part), "=")
+		if ok {
+			params[strings.ToLower(key)] = strings.Trim(value, `"`)
+		}
+	}
+	return strings.ToLower(scheme), params
+}
+
+// credentials looks up inline "auth" credentials from the docker config.
+// Credential helpers are not consulted.
+func (r *Resolver) credentials(host string) (string, string, bool) {
+	path := r.DockerConfig
+	if path == "" {
+		dir := os.Getenv("DOCKER_CONFIG")
+		if dir == "" {
+			home, err := os.UserHomeDir()
+			if err != nil {
+				return "", "", false
+			}
+			dir = filepath.Join(home, ".docker")
+		}
+		path = filepath.Join(dir, "config.json")
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", "", false
+	}
+	var config struct {
+		Auths map[string]struct {
+			Auth string `json:"auth"`
+		} `json:"auths"`
+	}
+	if err := json.Unmarshal(data, &config); err != nil {
+		return "", "", false
+	}
+	keys := []string{host, "https://" + host}
+	if host == "registry-1.docker.io" {
+		keys = append(keys, "https://index.docker.io/v1/", "docker.io")
+	}
+	for _, key := range keys {
+		entry, ok := config.Auths[key]
+		if !ok || entry.Auth == "" {
+			continue
+		}
+		decoded, err := base64.StdEncoding.DecodeString(entry.Auth)
+		if err != nil {
+			continue
+		}
+		username, password, ok := strings.Cut(string(decoded), ":")
+		if ok {
+			return username, password, true
+		}
+	}
+	return "", "", false
+}
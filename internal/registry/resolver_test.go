@@ -0,0 +1,127 @@
+package registry_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/middle-management/kubepose/internal/registry"
+)
+
+const appDigest = "sha256:0123456789abcdef0123456789abcdef0123456789abcdef0123456789abcdef"
+
+// newRegistry starts a registry stand-in serving team/app:v1 behind a bearer
+// token challenge, counting manifest requests.
+func newRegistry(t *testing.T, requests *int) *httptest.Server {
+	t.Helper()
+	var srv *httptest.Server
+	srv = httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.URL.Path == "/token":
+			if r.URL.Query().Get("scope") != "repository:team/app:pull" {
+				http.Error(w, "bad scope", http.StatusBadRequest)
+				return
+			}
+			json.NewEncoder(w).Encode(map[string]string{"token": "secret"})
+		case strings.HasPrefix(r.URL.Path, "/v2/"):
+			*requests++
+			if r.Header.Get("Authorization") != "Bearer secret" {
+				w.Header().Set("WWW-Authenticate", `Bearer realm="`+srv.URL+`/token",service="test"`)
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+			if r.URL.Path != "/v2/team/app/manifests/v1" {
+				w.WriteHeader(http.StatusNotFound)
+				return
+			}
+			w.Header().Set("Docker-Content-Digest", appDigest)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func TestResolveDigest(t *testing.T) {
+	t.Parallel()
+
+	var requests int
+	srv := newRegistry(t, &requests)
+	host := strings.TrimPrefix(srv.URL, "https://")
+	cache := filepath.Join(t.TempDir(), "digests.json")
+
+	resolver := &registry.Resolver{Client: srv.Client(), CacheFile: cache}
+	if err := resolver.Load(); err != nil {
+		t.Fatal(err)
+	}
+	digest, err := resolver.ResolveDigest(host + "/team/app:v1")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if digest != appDigest {
+		t.Fatalf("expected %s, got %s", appDigest, digest)
+	}
+	if _, err := resolver.ResolveDigest(host + "/team/app:v1"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	// One challenged request and one authorized one; the second lookup is
+	// served from memory.
+	if requests != 2 {
+		t.Fatalf("expected 2 manifest requests, got %d", requests)
+	}
+	if err := resolver.Save(); err != nil {
+		t.Fatal(err)
+	}
+
+	t.Run("offline resolves from the cache file", func(t *testing.T) {
+		offline := &registry.Resolver{CacheFile: cache, Offline: true}
+		if err := offline.Load(); err != nil {
+			t.Fatal(err)
+		}
+		digest, err := offline.ResolveDigest(host + "/team/app:v1")
+		if err != nil || digest != appDigest {
+			t.Fatalf("expected cached digest, got %q, %v", digest, err)
+		}
+		_, err = offline.ResolveDigest(host + "/team/app:v2")
+		if err == nil || !strings.Contains(err.Error(), "offline") {
+			t.Fatalf("expected offline cache miss error, got: %v", err)
+		}
+	})
+
+	t.Run("digest references resolve to their digest", func(t *testing.T) {
+		resolver := &registry.Resolver{Client: srv.Client(), Offline: true}
+		for _, image := range []string{host + "/team/app@" + appDigest, "nginx:1.27@" + appDigest} {
+			digest, err := resolver.ResolveDigest(image)
+			if err != nil || digest != appDigest {
+				t.Fatalf("%s: expected %s, got %q, %v", image, appDigest, digest, err)
+			}
+		}
+	})
+
+	t.Run("unknown tag returns error", func(t *testing.T) {
+		resolver := &registry.Resolver{Client: srv.Client()}
+		_, err := resolver.ResolveDigest(host + "/team/app:missing")
+		if err == nil || !strings.Contains(err.Error(), "tag missing not found") {
+			t.Fatalf("expected not found error, got: %v", err)
+		}
+	})
+}
+
+func TestLoadMissingCacheFile(t *testing.T) {
+	t.Parallel()
+	resolver := &registry.Resolver{CacheFile: filepath.Join(t.TempDir(), "absent.json")}
+	if err := resolver.Load(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := resolver.Save(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(resolver.CacheFile); !os.IsNotExist(err) {
+		t.Fatalf("expected no cache file without resolved digests, got: %v", err)
+	}
+}
@@ -0,0 +1,116 @@
+package sealedsecrets
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/pem"
+	"fmt"
+	"io"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// sessionKeyBytes is the AES-256 key size the controller expects.
+const sessionKeyBytes = 32
+
+// ParsePublicKey reads the controller's RSA public key from a PEM encoded
+// certificate, as printed by `kubeseal --fetch-cert`.
+func ParsePublicKey(data []byte) (*rsa.PublicKey, error) {
+	block, _ := pem.Decode(data)
+	if block == nil || block.Type != "CERTIFICATE" {
+		return nil, fmt.Errorf("expected a PEM encoded certificate")
+	}
+	cert, err := x509.ParseCertificate(block.Bytes)
+	if err != nil {
+		return nil, err
+	}
+	key, ok := cert.PublicKey.(*rsa.PublicKey)
+	if !ok {
+		return nil, fmt.Errorf("expected an RSA certificate, got %T", cert.PublicKey)
+	}
+	return key, nil
+}
+
+// HybridEncrypt encrypts plaintext the way the sealed-secrets controller
+// decrypts it: a random AES-256-GCM session key, itself encrypted with
+// RSA-OAEP (SHA-256) under label, prefixed by its big-endian uint16 length.
+// The session key is used once, so the GCM nonce is all zeros.
+func HybridEncrypt(rnd io.Reader, pubKey *rsa.PublicKey, plaintext, label []byte) ([]byte, error) {
+	sessionKey := make([]byte, sessionKeyBytes)
+	if _, err := io.ReadFull(rnd, sessionKey); err != nil {
+		return nil, err
+	}
+	block, err := aes.NewCipher(sessionKey)
+	if err != nil {
+		return nil, err
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+	rsaCiphertext, err := rsa.EncryptOAEP(sha256.New(), rnd, pubKey, sessionKey, label)
+	if err != nil {
+		return nil, err
+	}
+
+	ciphertext := binary.BigEndian.AppendUint16(nil, uint16(len(rsaCiphertext)))
+	ciphertext = append(ciphertext, rsaCiphertext...)
+	return aead.Seal(ciphertext, make([]byte, aead.NonceSize()), plaintext, nil), nil
+}
+
+// Seal converts a Secret into a SealedSecret. With a namespace the secret is
+// sealed in strict scope, unsealable only under its name in that namespace;
+// without one it is sealed cluster-wide.
+func Seal(rnd io.Reader, pubKey *rsa.PublicKey, secret *corev1.Secret, namespace string) (*SealedSecret, error) {
+	var label []byte
+	annotations := map[string]string{}
+	for k, v := range secret.Annotations {
+		annotations[k] = v
+	}
+	if namespace != "" {
+		label = []byte(namespace + "/" + secret.Name)
+	} else {
+		annotations[ClusterWideAnnotationKey] = "true"
+	}
+
+	encrypted := make(map[string]string, len(secret.Data))
+	for key, value := range secret.Data {
+		ciphertext, err := HybridEncrypt(rnd, pubKey, value, label)
+		if err != nil {
+			return nil, fmt.Errorf("sealing %s key %s: %w", secret.Name, key, err)
+		}
+		encrypted[key] = base64.StdEncoding.EncodeToString(ciphertext)
+	}
+
+	return &SealedSecret{
+		TypeMeta: metav1.TypeMeta{
+			APIVersion: SchemeGroupVersion.String(),
+			Kind:       "SealedSecret",
+		},
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        secret.Name,
+			Namespace:   namespace,
+			Labels:      secret.Labels,
+			Annotations: annotations,
+		},
+		Spec: SealedSecretSpec{
+			Template: SecretTemplateSpec{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:        secret.Name,
+					Namespace:   namespace,
+					Labels:      secret.Labels,
+					Annotations: secret.Annotations,
+				},
+				Type:      secret.Type,
+				Immutable: secret.Immutable,
+			},
+			EncryptedData: encrypted,
+		},
+	}, nil
+}
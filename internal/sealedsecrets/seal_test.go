@@ -0,0 +1,132 @@
+package sealedsecrets
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/pem"
+	"math/big"
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/utils/ptr"
+)
+
+// hybridDecrypt mirrors the sealed-secrets controller's decryption.
+func hybridDecrypt(t *testing.T, key *rsa.PrivateKey, ciphertext, label []byte) []byte {
+	t.Helper()
+	n := int(binary.BigEndian.Uint16(ciphertext))
+	sessionKey, err := rsa.DecryptOAEP(sha256.New(), nil, key, ciphertext[2:2+n], label)
+	if err != nil {
+		t.Fatalf("decrypting session key: %v", err)
+	}
+	block, err := aes.NewCipher(sessionKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		t.Fatal(err)
+	}
+	plaintext, err := aead.Open(nil, make([]byte, aead.NonceSize()), ciphertext[2+n:], nil)
+	if err != nil {
+		t.Fatalf("decrypting data: %v", err)
+	}
+	return plaintext
+}
+
+func TestSeal(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	secret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        "db-password-0123abcd",
+			Labels:      map[string]string{"kubepose.secret": "db-password"},
+			Annotations: map[string]string{"kubepose.secret.hmacKey": "kubepose.secret.v1"},
+		},
+		Data:      map[string][]byte{"content": []byte("hunter2")},
+		Immutable: ptr.To(true),
+	}
+
+	t.Run("strict", func(t *testing.T) {
+		sealed, err := Seal(rand.Reader, &key.PublicKey, secret, "prod")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if sealed.Name != secret.Name || sealed.Namespace != "prod" {
+			t.Errorf("sealed as %s/%s, want prod/%s", sealed.Namespace, sealed.Name, secret.Name)
+		}
+		if _, ok := sealed.Annotations[ClusterWideAnnotationKey]; ok {
+			t.Errorf("strict scope must not be annotated cluster-wide")
+		}
+		if !*sealed.Spec.Template.Immutable || sealed.Spec.Template.Labels["kubepose.secret"] != "db-password" {
+			t.Errorf("template does not carry the Secret's metadata: %+v", sealed.Spec.Template)
+		}
+		ciphertext, err := base64.StdEncoding.DecodeString(sealed.Spec.EncryptedData["content"])
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := hybridDecrypt(t, key, ciphertext, []byte("prod/"+secret.Name)); string(got) != "hunter2" {
+			t.Errorf("decrypted %q, want %q", got, "hunter2")
+		}
+	})
+
+	t.Run("cluster-wide", func(t *testing.T) {
+		sealed, err := Seal(rand.Reader, &key.PublicKey, secret, "")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if sealed.Annotations[ClusterWideAnnotationKey] != "true" {
+			t.Errorf("expected the cluster-wide annotation, got %v", sealed.Annotations)
+		}
+		if _, ok := secret.Annotations[ClusterWideAnnotationKey]; ok {
+			t.Errorf("sealing modified the Secret's annotations")
+		}
+		ciphertext, err := base64.StdEncoding.DecodeString(sealed.Spec.EncryptedData["content"])
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := hybridDecrypt(t, key, ciphertext, nil); string(got) != "hunter2" {
+			t.Errorf("decrypted %q, want %q", got, "hunter2")
+		}
+	})
+}
+
+func TestParsePublicKey(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "sealed-secret"},
+		NotBefore:    time.Now(),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	parsed, err := ParsePublicKey(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !parsed.Equal(&key.PublicKey) {
+		t.Errorf("parsed a different public key")
+	}
+
+	if _, err := ParsePublicKey([]byte("not a certificate")); err == nil {
+		t.Errorf("expected an error for non-PEM input")
+	}
+}
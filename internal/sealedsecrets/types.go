@@ -0,0 +1,56 @@
+// Package sealedsecrets holds the Bitnami sealed-secrets bitnami.com/v1alpha1
+// SealedSecret type and the controller's hybrid encryption, so Secrets can be
+// sealed offline from the controller's public certificate.
+package sealedsecrets
+
+import (
+	"encoding/json"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+// SchemeGroupVersion is the API group and version of SealedSecret.
+var SchemeGroupVersion = schema.GroupVersion{Group: "bitnami.com", Version: "v1alpha1"}
+
+// ClusterWideAnnotationKey marks a SealedSecret sealed without a namespace or
+// name in its label, so it can be unsealed under any name and namespace.
+const ClusterWideAnnotationKey = "sealedsecrets.bitnami.com/cluster-wide"
+
+// SealedSecret holds Secret data only the sealed-secrets controller can
+// decrypt; the controller creates the Secret described by the template.
+type SealedSecret struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec SealedSecretSpec `json:"spec"`
+}
+
+type SealedSecretSpec struct {
+	Template      SecretTemplateSpec `json:"template,omitempty"`
+	EncryptedData map[string]string  `json:"encryptedData"`
+}
+
+// SecretTemplateSpec describes the Secret the controller creates.
+type SecretTemplateSpec struct {
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Type      corev1.SecretType `json:"type,omitempty"`
+	Immutable *bool             `json:"immutable,omitempty"`
+}
+
+func (in *SealedSecret) DeepCopyObject() runtime.Object {
+	out := &SealedSecret{}
+	// These plain data types round-trip through JSON losslessly; this
+	// stands in for generated deepcopy functions.
+	data, err := json.Marshal(in)
+	if err != nil {
+		panic(err)
+	}
+	if err := json.Unmarshal(data, out); err != nil {
+		panic(err)
+	}
+	return out
+}
@@ -0,0 +1,394 @@
+package sops
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/sha512"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"hash"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"filippo.io/age"
+	"filippo.io/age/armor"
+	yamlv2 "go.yaml.in/yaml/v2"
+	"sigs.k8s.io/yaml"
+)
+
+// AgeKeyFileEnv names the environment variable holding the path of the age
+// identities file, as read by sops.
+const AgeKeyFileEnv = "SOPS_AGE_KEY_FILE"
+
+// Format is the file format of a SOPS file, which determines how it is
+// parsed and how its plaintext is written.
+type Format int
+
+const (
+	// Binary files are stored as a JSON document whose data key holds the
+	// encrypted content.
+	Binary Format = iota
+	YAML
+	JSON
+	Dotenv
+)
+
+// FormatForPath picks the format from the file extension, as sops does.
+func FormatForPath(path string) Format {
+	switch filepath.Ext(path) {
+	case ".yaml", ".yml":
+		return YAML
+	case ".json":
+		return JSON
+	case ".env":
+		return Dotenv
+	}
+	return Binary
+}
+
+var encValue = regexp.MustCompile(`^ENC\[AES256_GCM,data:(.*),iv:(.*),tag:(.*),type:(.*)\]$`)
+
+// dotenvAgeKey matches the flattened age key entries in dotenv metadata.
+var dotenvAgeKey = regexp.MustCompile(`^sops_age__list_(\d+)__map_(recipient|enc)$`)
+
+// IsEncrypted reports whether data is a SOPS file of the given format, i.e.
+// carries SOPS metadata with a MAC.
+func IsEncrypted(data []byte, format Format) bool {
+	_, meta, err := parse(data, format)
+	return err == nil && meta != nil && meta.MAC != ""
+}
+
+// LoadIdentities reads the age identities from the file named by
+// SOPS_AGE_KEY_FILE.
+func LoadIdentities() ([]age.Identity, error) {
+	path := os.Getenv(AgeKeyFileEnv)
+	if path == "" {
+		return nil, fmt.Errorf("%s is not set", AgeKeyFileEnv)
+	}
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	identities, err := age.ParseIdentities(file)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return identities, nil
+}
+
+// Decrypt decrypts a SOPS file encrypted for age and returns its plaintext in
+// the same format, without the SOPS metadata. The MAC is verified, so a
+// tampered file is rejected.
+func Decrypt(data []byte, format Format, identities []age.Identity) ([]byte, error) {
+	tree, meta, err := parse(data, format)
+	if err != nil {
+		return nil, err
+	}
+	if meta == nil || meta.MAC == "" {
+		return nil, fmt.Errorf("not a SOPS file: no sops metadata")
+	}
+
+	key, err := decryptDataKey(meta.Age, identities)
+	if err != nil {
+		return nil, err
+	}
+
+	mac := sha512.New()
+	plain, err := decryptValue(tree, nil, key, mac, meta.MACOnlyEncrypted)
+	if err != nil {
+		return nil, err
+	}
+	wantMAC, _, err := decryptLeaf(meta.MAC, key, meta.LastModified)
+	if err != nil {
+		return nil, fmt.Errorf("decrypting MAC: %w", err)
+	}
+	if fmt.Sprintf("%X", mac.Sum(nil)) != wantMAC {
+		return nil, fmt.Errorf("MAC mismatch: the file was modified after encryption")
+	}
+
+	return emit(plain.(yamlv2.MapSlice), format)
+}
+
+// parse splits a SOPS file into its tree, in document order, and its
+// metadata, which is nil when the file has none.
+func parse(data []byte, format Format) (yamlv2.MapSlice, *metadata, error) {
+	if format == Dotenv {
+		return parseDotenv(data)
+	}
+
+	// JSON is YAML, and the YAML decoder keeps the key order.
+	var document yamlv2.MapSlice
+	if err := yamlv2.Unmarshal(data, &document); err != nil {
+		return nil, nil, err
+	}
+	var tree yamlv2.MapSlice
+	var metaValue any
+	for _, item := range document {
+		if item.Key == metadataKey {
+			metaValue = item.Value
+			continue
+		}
+		tree = append(tree, item)
+	}
+	if metaValue == nil {
+		return tree, nil, nil
+	}
+
+	metaYAML, err := yamlv2.Marshal(metaValue)
+	if err != nil {
+		return nil, nil, err
+	}
+	var meta metadata
+	if err := yaml.Unmarshal(metaYAML, &meta); err != nil {
+		return nil, nil, fmt.Errorf("invalid sops metadata: %w", err)
+	}
+	return tree, &meta, nil
+}
+
+// parseDotenv reads KEY=VALUE lines. Metadata is flattened into sops_
+// prefixed keys, and newlines in values are escaped as \n.
+func parseDotenv(data []byte) (yamlv2.MapSlice, *metadata, error) {
+	var tree yamlv2.MapSlice
+	var meta *metadata
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		line := scanner.Text()
+		if strings.TrimSpace(line) == "" || strings.HasPrefix(line, "#") {
+			// Comments are not part of the MAC; they are dropped.
+			continue
+		}
+		key, value, ok := strings.Cut(line, "=")
+		if !ok {
+			return nil, nil, fmt.Errorf("invalid dotenv line %q", line)
+		}
+		value = strings.ReplaceAll(value, `\n`, "\n")
+		if !strings.HasPrefix(key, metadataKey+"_") {
+			tree = append(tree, yamlv2.MapItem{Key: key, Value: value})
+			continue
+		}
+
+		if meta == nil {
+			meta = &metadata{}
+		}
+		if m := dotenvAgeKey.FindStringSubmatch(key); m != nil {
+			i, _ := strconv.Atoi(m[1])
+			for len(meta.Age) <= i {
+				meta.Age = append(meta.Age, ageKey{})
+			}
+			if m[2] == "recipient" {
+				meta.Age[i].Recipient = value
+			} else {
+				meta.Age[i].Enc = value
+			}
+			continue
+		}
+		switch strings.TrimPrefix(key, metadataKey+"_") {
+		case "lastmodified":
+			meta.LastModified = value
+		case "mac":
+			meta.MAC = value
+		case "mac_only_encrypted":
+			meta.MACOnlyEncrypted = value == "true"
+		}
+	}
+	return tree, meta, scanner.Err()
+}
+
+// decryptDataKey unwraps the data key from the first age key entry one of
+// the identities can decrypt.
+func decryptDataKey(keys []ageKey, identities []age.Identity) ([]byte, error) {
+	if len(keys) == 0 {
+		return nil, fmt.Errorf("the file is not encrypted for any age recipient")
+	}
+	var recipients []string
+	for _, k := range keys {
+		r, err := age.Decrypt(armor.NewReader(strings.NewReader(k.Enc)), identities...)
+		if err != nil {
+			recipients = append(recipients, k.Recipient)
+			continue
+		}
+		return io.ReadAll(r)
+	}
+	return nil, fmt.Errorf("no age identity matches the recipients %s", strings.Join(recipients, ", "))
+}
+
+// decryptValue mirrors encryptValue: encrypted leaves are decrypted with
+// their key path as additional data, and the plaintext of every leaf, or
+// only of encrypted ones with mac_only_encrypted, is added to the MAC.
+func decryptValue(value any, path []string, key []byte, mac hash.Hash, macOnlyEncrypted bool) (any, error) {
+	switch value := value.(type) {
+	case yamlv2.MapSlice:
+		out := make(yamlv2.MapSlice, len(value))
+		for i, item := range value {
+			k := fmt.Sprint(item.Key)
+			v, err := decryptValue(item.Value, append(path[:len(path):len(path)], k), key, mac, macOnlyEncrypted)
+			if err != nil {
+				return nil, err
+			}
+			out[i] = yamlv2.MapItem{Key: item.Key, Value: v}
+		}
+		return out, nil
+	case []any:
+		out := make([]any, len(value))
+		for i, item := range value {
+			v, err := decryptValue(item, path, key, mac, macOnlyEncrypted)
+			if err != nil {
+				return nil, err
+			}
+			out[i] = v
+		}
+		return out, nil
+	case nil:
+		return nil, nil
+	}
+
+	encrypted := false
+	if s, ok := value.(string); ok && encValue.MatchString(s) {
+		plaintext, valueType, err := decryptLeaf(s, key, strings.Join(path, ":")+":")
+		if err != nil {
+			return nil, fmt.Errorf("decrypting %s: %w", strings.Join(path, "."), err)
+		}
+		if value, err = typedValue(plaintext, valueType); err != nil {
+			return nil, fmt.Errorf("decrypting %s: %w", strings.Join(path, "."), err)
+		}
+		encrypted = true
+	}
+
+	if encrypted || !macOnlyEncrypted {
+		plaintext, _, err := leafBytes(value)
+		if err != nil {
+			return nil, err
+		}
+		mac.Write(macBytes(value, plaintext))
+	}
+	return value, nil
+}
+
+// decryptLeaf opens a SOPS ENC[...] value and returns its plaintext and type.
+func decryptLeaf(value string, key []byte, additionalData string) (string, string, error) {
+	m := encValue.FindStringSubmatch(value)
+	if m == nil {
+		return "", "", fmt.Errorf("not an encrypted value")
+	}
+	var parts [3][]byte
+	for i := range parts {
+		b, err := base64.StdEncoding.DecodeString(m[i+1])
+		if err != nil {
+			return "", "", err
+		}
+		parts[i] = b
+	}
+	data, iv, tag := parts[0], parts[1], parts[2]
+
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return "", "", err
+	}
+	gcm, err := cipher.NewGCMWithNonceSize(block, len(iv))
+	if err != nil {
+		return "", "", err
+	}
+	plaintext, err := gcm.Open(nil, iv, append(data, tag...), []byte(additionalData))
+	if err != nil {
+		return "", "", err
+	}
+	return string(plaintext), m[4], nil
+}
+
+func typedValue(plaintext, valueType string) (any, error) {
+	switch valueType {
+	case "str", "bytes":
+		return plaintext, nil
+	case "int":
+		return strconv.Atoi(plaintext)
+	case "float":
+		return strconv.ParseFloat(plaintext, 64)
+	case "bool":
+		return strconv.ParseBool(plaintext)
+	}
+	return nil, fmt.Errorf("unsupported value type %q", valueType)
+}
+
+// emit writes the decrypted tree in its file format.
+func emit(tree yamlv2.MapSlice, format Format) ([]byte, error) {
+	switch format {
+	case YAML:
+		return yamlv2.Marshal(tree)
+	case JSON:
+		var buf bytes.Buffer
+		if err := writeJSON(&buf, tree); err != nil {
+			return nil, err
+		}
+		var out bytes.Buffer
+		if err := json.Indent(&out, buf.Bytes(), "", "\t"); err != nil {
+			return nil, err
+		}
+		out.WriteByte('\n')
+		return out.Bytes(), nil
+	case Dotenv:
+		var out bytes.Buffer
+		for _, item := range tree {
+			fmt.Fprintf(&out, "%v=%s\n", item.Key, strings.ReplaceAll(fmt.Sprint(item.Value), "\n", `\n`))
+		}
+		return out.Bytes(), nil
+	}
+
+	for _, item := range tree {
+		if item.Key == "data" {
+			if data, ok := item.Value.(string); ok {
+				return []byte(data), nil
+			}
+		}
+	}
+	return nil, fmt.Errorf("binary SOPS file has no data value")
+}
+
+// writeJSON encodes a decoded tree as JSON, keeping the key order.
+func writeJSON(buf *bytes.Buffer, value any) error {
+	switch value := value.(type) {
+	case yamlv2.MapSlice:
+		buf.WriteByte('{')
+		for i, item := range value {
+			if i > 0 {
+				buf.WriteByte(',')
+			}
+			key, err := json.Marshal(fmt.Sprint(item.Key))
+			if err != nil {
+				return err
+			}
+			buf.Write(key)
+			buf.WriteByte(':')
+			if err := writeJSON(buf, item.Value); err != nil {
+				return err
+			}
+		}
+		buf.WriteByte('}')
+		return nil
+	case []any:
+		buf.WriteByte('[')
+		for i, item := range value {
+			if i > 0 {
+				buf.WriteByte(',')
+			}
+			if err := writeJSON(buf, item); err != nil {
+				return err
+			}
+		}
+		buf.WriteByte(']')
+		return nil
+	}
+	data, err := json.Marshal(value)
+	if err != nil {
+		return err
+	}
+	buf.Write(data)
+	return nil
+}
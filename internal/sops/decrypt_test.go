@@ -0,0 +1,242 @@
+package sops
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"filippo.io/age"
+	yamlv2 "go.yaml.in/yaml/v2"
+	"sigs.k8s.io/yaml"
+)
+
+// encryptFixture encrypts tree, whose keys must be sorted so the document
+// order of the marshaled file matches the MAC, for identity.
+func encryptFixture(t *testing.T, tree yamlv2.MapSlice, identity *age.X25519Identity) map[string]any {
+	t.Helper()
+	document, err := encryptDocument(tree, []string{identity.Recipient().String()}, nil, time.Now())
+	if err != nil {
+		t.Fatal(err)
+	}
+	return document
+}
+
+// dotenvFixture flattens an encrypted document the way the sops dotenv
+// store does.
+func dotenvFixture(document map[string]any) string {
+	var lines []string
+	for k, v := range document {
+		if k == metadataKey {
+			continue
+		}
+		lines = append(lines, fmt.Sprintf("%s=%s", k, strings.ReplaceAll(fmt.Sprint(v), "\n", `\n`)))
+	}
+	sort.Strings(lines)
+	meta := document[metadataKey].(metadata)
+	for i, k := range meta.Age {
+		lines = append(lines,
+			fmt.Sprintf("sops_age__list_%d__map_enc=%s", i, strings.ReplaceAll(k.Enc, "\n", `\n`)),
+			fmt.Sprintf("sops_age__list_%d__map_recipient=%s", i, k.Recipient))
+	}
+	lines = append(lines,
+		"sops_lastmodified="+meta.LastModified,
+		"sops_mac="+meta.MAC,
+		"sops_version="+meta.Version)
+	return "# comment\n" + strings.Join(lines, "\n") + "\n"
+}
+
+func TestDecrypt(t *testing.T) {
+	identity, err := age.GenerateX25519Identity()
+	if err != nil {
+		t.Fatal(err)
+	}
+	config := yamlv2.MapSlice{
+		{Key: "db", Value: yamlv2.MapSlice{
+			{Key: "enabled", Value: true},
+			{Key: "hosts", Value: []any{"a", "b"}},
+			{Key: "password", Value: "hunter2"},
+			{Key: "port", Value: 5432},
+		}},
+		{Key: "name", Value: "app"},
+	}
+
+	yamlFile, err := yaml.Marshal(encryptFixture(t, config, identity))
+	if err != nil {
+		t.Fatal(err)
+	}
+	jsonFile, err := json.Marshal(encryptFixture(t, config, identity))
+	if err != nil {
+		t.Fatal(err)
+	}
+	binaryFile, err := json.Marshal(encryptFixture(t, yamlv2.MapSlice{{Key: "data", Value: "line 1\nline 2"}}, identity))
+	if err != nil {
+		t.Fatal(err)
+	}
+	dotenvFile := dotenvFixture(encryptFixture(t, yamlv2.MapSlice{
+		{Key: "PASSWORD", Value: "hunter2"},
+		{Key: "TLS_KEY", Value: "line 1\nline 2"},
+	}, identity))
+
+	tests := []struct {
+		name   string
+		data   string
+		format Format
+		want   string
+	}{
+		{
+			name:   "yaml",
+			data:   string(yamlFile),
+			format: YAML,
+			want:   "db:\n  enabled: true\n  hosts:\n  - a\n  - b\n  password: hunter2\n  port: 5432\nname: app\n",
+		},
+		{
+			name:   "json",
+			data:   string(jsonFile),
+			format: JSON,
+			want:   "{\n\t\"db\": {\n\t\t\"enabled\": true,\n\t\t\"hosts\": [\n\t\t\t\"a\",\n\t\t\t\"b\"\n\t\t],\n\t\t\"password\": \"hunter2\",\n\t\t\"port\": 5432\n\t},\n\t\"name\": \"app\"\n}\n",
+		},
+		{
+			name:   "dotenv",
+			data:   dotenvFile,
+			format: Dotenv,
+			want:   "PASSWORD=hunter2\nTLS_KEY=line 1\\nline 2\n",
+		},
+		{
+			name:   "binary",
+			data:   string(binaryFile),
+			format: Binary,
+			want:   "line 1\nline 2",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if !IsEncrypted([]byte(tt.data), tt.format) {
+				t.Fatalf("expected the file to be detected as encrypted:\n%s", tt.data)
+			}
+			got, err := Decrypt([]byte(tt.data), tt.format, []age.Identity{identity})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(got) != tt.want {
+				t.Errorf("got:\n%s\nwant:\n%s", got, tt.want)
+			}
+		})
+	}
+
+	t.Run("tampered file fails the MAC", func(t *testing.T) {
+		// Values cannot be altered without the data key, but they can be
+		// dropped.
+		var lines []string
+		for _, line := range strings.Split(string(yamlFile), "\n") {
+			if !strings.HasPrefix(line, "name: ") {
+				lines = append(lines, line)
+			}
+		}
+		_, err := Decrypt([]byte(strings.Join(lines, "\n")), YAML, []age.Identity{identity})
+		if err == nil || !strings.Contains(err.Error(), "MAC mismatch") {
+			t.Fatalf("expected a MAC mismatch, got %v", err)
+		}
+	})
+
+	t.Run("unknown identity", func(t *testing.T) {
+		other, err := age.GenerateX25519Identity()
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, err = Decrypt(yamlFile, YAML, []age.Identity{other})
+		if err == nil || !strings.Contains(err.Error(), "no age identity matches the recipients "+identity.Recipient().String()) {
+			t.Fatalf("expected an identity error, got %v", err)
+		}
+	})
+}
+
+// TestDecryptSopsFiles decrypts files written by sops 3.10.2 itself
+// (`sops --encrypt --age <recipient>`) for the test key in
+// testdata/sops-secrets/age.key.
+func TestDecryptSopsFiles(t *testing.T) {
+	keyFile, err := os.Open("../../testdata/sops-secrets/age.key")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer keyFile.Close()
+	identities, err := age.ParseIdentities(keyFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, tt := range []struct {
+		file string
+		want string
+	}{
+		{
+			file: "config.yaml",
+			want: "db:\n  enabled: true\n  hosts:\n  - a\n  - b\n  password: hunter2\n  port: 5432\n  ratio: 0.5\nname: app\nregion_unencrypted: eu-north-1\n",
+		},
+		{
+			file: "config.json",
+			want: "{\n\t\"db\": {\n\t\t\"enabled\": true,\n\t\t\"hosts\": [\n\t\t\t\"a\",\n\t\t\t\"b\"\n\t\t],\n\t\t\"password\": \"hunter2\",\n\t\t\"port\": 5432\n\t},\n\t\"name\": \"app\"\n}\n",
+		},
+		{
+			file: "prod.env",
+			want: "PASSWORD=hunter2\nTLS_KEY=line 1\\nline 2\n",
+		},
+		{
+			file: "tls.key",
+			want: "line 1\nline 2",
+		},
+	} {
+		t.Run(tt.file, func(t *testing.T) {
+			data, err := os.ReadFile(filepath.Join("testdata", tt.file))
+			if err != nil {
+				t.Fatal(err)
+			}
+			format := FormatForPath(tt.file)
+			if !IsEncrypted(data, format) {
+				t.Fatalf("expected %s to be detected as encrypted", tt.file)
+			}
+			got, err := Decrypt(data, format, identities)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(got) != tt.want {
+				t.Errorf("got:\n%s\nwant:\n%s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestIsEncrypted(t *testing.T) {
+	for _, tt := range []struct {
+		data   string
+		format Format
+	}{
+		{"password: hunter2\n", YAML},
+		{`{"password": "hunter2"}`, JSON},
+		{"PASSWORD=hunter2\n", Dotenv},
+		{"hunter2", Binary},
+		{"\x00\x01\x02", Binary},
+	} {
+		if IsEncrypted([]byte(tt.data), tt.format) {
+			t.Errorf("%q must not be detected as encrypted", tt.data)
+		}
+	}
+}
+
+func TestFormatForPath(t *testing.T) {
+	for path, want := range map[string]Format{
+		"secrets/db.enc.yaml": YAML,
+		"db.yml":              YAML,
+		"db.json":             JSON,
+		"prod.env":            Dotenv,
+		"tls.key":             Binary,
+	} {
+		if got := FormatForPath(path); got != want {
+			t.Errorf("FormatForPath(%q) = %v, want %v", path, got, want)
+		}
+	}
+}
@@ -0,0 +1,282 @@
+// Package sops writes Kubernetes Secrets as SOPS-encrypted documents for age
+// recipients, in the format `sops --encrypt --encrypted-regex
+// '^(data|stringData)$'` produces, so Flux and other SOPS-aware tooling can
+// decrypt them in the cluster. It also decrypts SOPS files encrypted for
+// age, as `sops --decrypt` does.
+package sops
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha512"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"hash"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"filippo.io/age"
+	"filippo.io/age/armor"
+	yamlv2 "go.yaml.in/yaml/v2"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/yaml"
+)
+
+const (
+	// EncryptedRegex selects the keys whose values are encrypted; metadata
+	// stays readable so tooling can still route the document.
+	EncryptedRegex = "^(data|stringData)$"
+	// Version is the SOPS version recorded in the metadata, the oldest
+	// release whose format this package writes.
+	Version = "3.9.0"
+
+	// metadataKey is the top-level key holding the SOPS metadata.
+	metadataKey = "sops"
+	// ivBytes is the GCM nonce size SOPS uses.
+	ivBytes = 32
+)
+
+var encryptedRegex = regexp.MustCompile(EncryptedRegex)
+
+// Secret is a Secret whose data and stringData are SOPS-encrypted. It keeps
+// the plaintext Secret for its name and kind; only the encrypted document is
+// marshaled.
+type Secret struct {
+	*corev1.Secret
+
+	document map[string]any
+}
+
+func (s *Secret) MarshalJSON() ([]byte, error) {
+	return json.Marshal(s.document)
+}
+
+// DeepCopyObject copies the Secret without ever handing out the plaintext as
+// a plain corev1.Secret.
+func (s *Secret) DeepCopyObject() runtime.Object {
+	return &Secret{Secret: s.Secret.DeepCopy(), document: s.document}
+}
+
+type ageKey struct {
+	Recipient string `json:"recipient"`
+	Enc       string `json:"enc"`
+}
+
+type metadata struct {
+	Age              []ageKey `json:"age"`
+	LastModified     string   `json:"lastmodified"`
+	MAC              string   `json:"mac"`
+	MACOnlyEncrypted bool     `json:"mac_only_encrypted,omitempty"`
+	EncryptedRegex   string   `json:"encrypted_regex,omitempty"`
+	Version          string   `json:"version"`
+}
+
+// Encrypt encrypts secret for the age recipients ("age1...") under a fresh
+// data key. Every run produces different ciphertext.
+func Encrypt(secret *corev1.Secret, recipients []string, now time.Time) (*Secret, error) {
+	// The MAC covers the values in document order, which for kubepose
+	// output is the key order of its YAML encoding; decode that encoding in
+	// order instead of guessing it.
+	plaintext, err := yaml.Marshal(secret)
+	if err != nil {
+		return nil, err
+	}
+	var tree yamlv2.MapSlice
+	if err := yamlv2.Unmarshal(plaintext, &tree); err != nil {
+		return nil, err
+	}
+	document, err := encryptDocument(tree, recipients, encryptedRegex, now)
+	if err != nil {
+		return nil, err
+	}
+	return &Secret{Secret: secret, document: document}, nil
+}
+
+// encryptDocument encrypts the values of tree below a key matching regex,
+// or all values if regex is nil, and returns it with its SOPS metadata.
+func encryptDocument(tree yamlv2.MapSlice, recipients []string, regex *regexp.Regexp, now time.Time) (map[string]any, error) {
+	if len(recipients) == 0 {
+		return nil, fmt.Errorf("at least one age recipient is required")
+	}
+	dataKey := make([]byte, 32)
+	if _, err := rand.Read(dataKey); err != nil {
+		return nil, err
+	}
+
+	var keys []ageKey
+	for _, recipient := range recipients {
+		enc, err := encryptDataKey(dataKey, recipient)
+		if err != nil {
+			return nil, fmt.Errorf("age recipient %q: %w", recipient, err)
+		}
+		keys = append(keys, ageKey{Recipient: recipient, Enc: enc})
+	}
+
+	mac := sha512.New()
+	encrypted, err := encryptValue(tree, nil, dataKey, regex, mac)
+	if err != nil {
+		return nil, err
+	}
+
+	lastModified := now.UTC().Format(time.RFC3339)
+	encryptedMAC, err := encryptLeaf(fmt.Sprintf("%X", mac.Sum(nil)), dataKey, lastModified)
+	if err != nil {
+		return nil, err
+	}
+
+	meta := metadata{
+		Age:          keys,
+		LastModified: lastModified,
+		MAC:          encryptedMAC,
+		Version:      Version,
+	}
+	if regex != nil {
+		meta.EncryptedRegex = regex.String()
+	}
+	document := encrypted.(map[string]any)
+	document[metadataKey] = meta
+	return document, nil
+}
+
+// encryptDataKey wraps the data key for one recipient as an armored age file.
+func encryptDataKey(dataKey []byte, recipient string) (string, error) {
+	r, err := age.ParseX25519Recipient(recipient)
+	if err != nil {
+		return "", err
+	}
+	var buf bytes.Buffer
+	armored := armor.NewWriter(&buf)
+	w, err := age.Encrypt(armored, r)
+	if err != nil {
+		return "", err
+	}
+	if _, err := w.Write(dataKey); err != nil {
+		return "", err
+	}
+	if err := w.Close(); err != nil {
+		return "", err
+	}
+	if err := armored.Close(); err != nil {
+		return "", err
+	}
+	return buf.String(), nil
+}
+
+// encryptValue walks a decoded YAML value the way SOPS does: every leaf is
+// added to the MAC, and leaves below a key matching regex are encrypted with
+// their key path as additional data. List items share the path of their
+// list. Mappings are returned as map[string]any for JSON.
+func encryptValue(value any, path []string, key []byte, regex *regexp.Regexp, mac hash.Hash) (any, error) {
+	switch value := value.(type) {
+	case yamlv2.MapSlice:
+		out := make(map[string]any, len(value))
+		for _, item := range value {
+			k := fmt.Sprint(item.Key)
+			v, err := encryptValue(item.Value, append(path[:len(path):len(path)], k), key, regex, mac)
+			if err != nil {
+				return nil, err
+			}
+			out[k] = v
+		}
+		return out, nil
+	case []any:
+		out := make([]any, len(value))
+		for i, item := range value {
+			v, err := encryptValue(item, path, key, regex, mac)
+			if err != nil {
+				return nil, err
+			}
+			out[i] = v
+		}
+		return out, nil
+	case nil:
+		// SOPS skips nulls, both for the MAC and for encryption.
+		return nil, nil
+	}
+
+	plaintext, valueType, err := leafBytes(value)
+	if err != nil {
+		return nil, err
+	}
+	mac.Write(macBytes(value, plaintext))
+
+	encrypt := regex == nil
+	for _, p := range path {
+		if regex != nil && regex.MatchString(p) {
+			encrypt = true
+			break
+		}
+	}
+	if !encrypt {
+		return value, nil
+	}
+	return encryptBytes(plaintext, valueType, key, strings.Join(path, ":")+":")
+}
+
+// leafBytes returns the plaintext SOPS encrypts for a scalar and its type tag.
+func leafBytes(value any) ([]byte, string, error) {
+	switch value := value.(type) {
+	case string:
+		return []byte(value), "str", nil
+	case int:
+		return []byte(strconv.Itoa(value)), "int", nil
+	case float64:
+		return []byte(strconv.FormatFloat(value, 'f', -1, 64)), "float", nil
+	case bool:
+		return []byte(strconv.FormatBool(value)), "bool", nil
+	}
+	return nil, "", fmt.Errorf("cannot encrypt value of type %T", value)
+}
+
+// macBytes returns the bytes SOPS hashes for a scalar, which differ from the
+// encrypted plaintext only for booleans.
+func macBytes(value any, plaintext []byte) []byte {
+	if b, ok := value.(bool); ok {
+		if b {
+			return []byte("True")
+		}
+		return []byte("False")
+	}
+	return plaintext
+}
+
+func encryptLeaf(value string, key []byte, additionalData string) (string, error) {
+	encrypted, err := encryptBytes([]byte(value), "str", key, additionalData)
+	if err != nil {
+		return "", err
+	}
+	return encrypted.(string), nil
+}
+
+// encryptBytes produces a SOPS ENC[...] value. Empty strings stay empty, as
+// in SOPS.
+func encryptBytes(plaintext []byte, valueType string, key []byte, additionalData string) (any, error) {
+	if len(plaintext) == 0 && valueType == "str" {
+		return "", nil
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	gcm, err := cipher.NewGCMWithNonceSize(block, ivBytes)
+	if err != nil {
+		return nil, err
+	}
+	iv := make([]byte, ivBytes)
+	if _, err := rand.Read(iv); err != nil {
+		return nil, err
+	}
+	sealed := gcm.Seal(nil, iv, plaintext, []byte(additionalData))
+	data, tag := sealed[:len(sealed)-gcm.Overhead()], sealed[len(sealed)-gcm.Overhead():]
+	return fmt.Sprintf("ENC[AES256_GCM,data:%s,iv:%s,tag:%s,type:%s]",
+		base64.StdEncoding.EncodeToString(data),
+		base64.StdEncoding.EncodeToString(iv),
+		base64.StdEncoding.EncodeToString(tag),
+		valueType), nil
+}
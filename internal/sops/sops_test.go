@@ -0,0 +1,108 @@
+package sops
+
+import (
+	"crypto/sha512"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"filippo.io/age"
+	"filippo.io/age/armor"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/utils/ptr"
+)
+
+// decrypt opens a SOPS ENC[...] value.
+func decrypt(t *testing.T, value string, key []byte, additionalData string) string {
+	t.Helper()
+	plaintext, _, err := decryptLeaf(value, key, additionalData)
+	if err != nil {
+		t.Fatalf("decrypting %s: %v", additionalData, err)
+	}
+	return plaintext
+}
+
+func TestEncrypt(t *testing.T) {
+	identity, err := age.GenerateX25519Identity()
+	if err != nil {
+		t.Fatal(err)
+	}
+	secret := &corev1.Secret{
+		TypeMeta: metav1.TypeMeta{APIVersion: "v1", Kind: "Secret"},
+		ObjectMeta: metav1.ObjectMeta{
+			Name:   "db-password-0123abcd",
+			Labels: map[string]string{"kubepose.secret": "db-password"},
+		},
+		Data:      map[string][]byte{"db-password": []byte("hunter2"), "empty": {}},
+		Immutable: ptr.To(true),
+	}
+	now := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+
+	encrypted, err := Encrypt(secret, []string{identity.Recipient().String()}, now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := json.Marshal(encrypted)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var document struct {
+		APIVersion string            `json:"apiVersion"`
+		Immutable  bool              `json:"immutable"`
+		Kind       string            `json:"kind"`
+		Metadata   metav1.ObjectMeta `json:"metadata"`
+		Data       map[string]string `json:"data"`
+		Sops       metadata          `json:"sops"`
+	}
+	if err := json.Unmarshal(data, &document); err != nil {
+		t.Fatal(err)
+	}
+
+	if document.Metadata.Name != secret.Name || document.Metadata.Labels["kubepose.secret"] != "db-password" {
+		t.Errorf("metadata must stay readable, got %+v", document.Metadata)
+	}
+	if document.Sops.LastModified != "2026-01-02T03:04:05Z" || document.Sops.EncryptedRegex != EncryptedRegex {
+		t.Errorf("unexpected sops metadata %+v", document.Sops)
+	}
+	if len(document.Sops.Age) != 1 || document.Sops.Age[0].Recipient != identity.Recipient().String() {
+		t.Fatalf("expected one age key for the recipient, got %+v", document.Sops.Age)
+	}
+
+	r, err := age.Decrypt(armor.NewReader(strings.NewReader(document.Sops.Age[0].Enc)), identity)
+	if err != nil {
+		t.Fatal(err)
+	}
+	key, err := io.ReadAll(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	password := decrypt(t, document.Data["db-password"], key, "data:db-password:")
+	if password != base64.StdEncoding.EncodeToString([]byte("hunter2")) {
+		t.Errorf("decrypted %q, want the base64 encoded secret", password)
+	}
+	if document.Data["empty"] != "" {
+		t.Errorf("empty values stay empty, got %q", document.Data["empty"])
+	}
+
+	// The MAC covers every value in document (sorted key) order.
+	mac := sha512.New()
+	for _, value := range []string{"v1", password, "", "True", "Secret", "db-password", secret.Name} {
+		mac.Write([]byte(value))
+	}
+	if got, want := decrypt(t, document.Sops.MAC, key, document.Sops.LastModified), fmt.Sprintf("%X", mac.Sum(nil)); got != want {
+		t.Errorf("MAC %s, want %s", got, want)
+	}
+}
+
+func TestEncryptInvalidRecipient(t *testing.T) {
+	_, err := Encrypt(&corev1.Secret{}, []string{"age1invalid"}, time.Now())
+	if err == nil || !strings.Contains(err.Error(), `age recipient "age1invalid"`) {
+		t.Errorf("expected an invalid recipient error, got %v", err)
+	}
+}
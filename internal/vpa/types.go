@@ -0,0 +1,72 @@
+// Package vpa holds the subset of the Kubernetes autoscaler's
+// autoscaling.k8s.io/v1 VerticalPodAutoscaler API that kubepose emits. The
+// upstream module lives in the autoscaler repository alongside the cluster
+// autoscaler and is not worth depending on for one manifest shape.
+package vpa
+
+import (
+	"encoding/json"
+
+	autoscalingv1 "k8s.io/api/autoscaling/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+// SchemeGroupVersion is the API group and version of VerticalPodAutoscaler.
+var SchemeGroupVersion = schema.GroupVersion{Group: "autoscaling.k8s.io", Version: "v1"}
+
+type UpdateMode string
+
+const (
+	UpdateModeOff               UpdateMode = "Off"
+	UpdateModeInitial           UpdateMode = "Initial"
+	UpdateModeRecreate          UpdateMode = "Recreate"
+	UpdateModeInPlaceOrRecreate UpdateMode = "InPlaceOrRecreate"
+	UpdateModeAuto              UpdateMode = "Auto"
+)
+
+// VerticalPodAutoscaler recommends, and depending on the update mode
+// applies, container resource requests for a workload.
+type VerticalPodAutoscaler struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec VerticalPodAutoscalerSpec `json:"spec"`
+}
+
+type VerticalPodAutoscalerSpec struct {
+	TargetRef      *autoscalingv1.CrossVersionObjectReference `json:"targetRef"`
+	UpdatePolicy   *PodUpdatePolicy                           `json:"updatePolicy,omitempty"`
+	ResourcePolicy *PodResourcePolicy                         `json:"resourcePolicy,omitempty"`
+}
+
+type PodUpdatePolicy struct {
+	UpdateMode *UpdateMode `json:"updateMode,omitempty"`
+}
+
+type PodResourcePolicy struct {
+	ContainerPolicies []ContainerResourcePolicy `json:"containerPolicies,omitempty"`
+}
+
+// ContainerResourcePolicy bounds the recommendations for one container.
+type ContainerResourcePolicy struct {
+	ContainerName string              `json:"containerName,omitempty"`
+	MinAllowed    corev1.ResourceList `json:"minAllowed,omitempty"`
+	MaxAllowed    corev1.ResourceList `json:"maxAllowed,omitempty"`
+}
+
+func (in *VerticalPodAutoscaler) DeepCopyObject() runtime.Object {
+	out := &VerticalPodAutoscaler{}
+	// These plain data types round-trip through JSON losslessly; this
+	// stands in for generated deepcopy functions.
+	data, err := json.Marshal(in)
+	if err != nil {
+		panic(err)
+	}
+	if err := json.Unmarshal(data, out); err != nil {
+		panic(err)
+	}
+	return out
+}
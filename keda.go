@@ -0,0 +1,201 @@
+package kubepose
+
+import (
+	"fmt"
+
+	"github.com/compose-spec/compose-go/v2/types"
+	"github.com/middle-management/kubepose/internal/keda"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/utils/ptr"
+)
+
+// kedaExtension is the x-kubepose.keda service extension. Long-running
+// services get a ScaledObject scaling their Deployment, run-once services a
+// ScaledJob that starts a Job per batch of events.
+type kedaExtension struct {
+	MinReplicaCount  *int32 `json:"minReplicaCount,omitempty"`
+	MaxReplicaCount  *int32 `json:"maxReplicaCount,omitempty"`
+	IdleReplicaCount *int32 `json:"idleReplicaCount,omitempty"`
+	PollingInterval  *int32 `json:"pollingInterval,omitempty"`
+	CooldownPeriod   *int32 `json:"cooldownPeriod,omitempty"`
+	// History limits only apply to ScaledJobs.
+	SuccessfulJobsHistoryLimit *int32               `json:"successfulJobsHistoryLimit,omitempty"`
+	FailedJobsHistoryLimit     *int32               `json:"failedJobsHistoryLimit,omitempty"`
+	Triggers                   []keda.ScaleTriggers `json:"triggers"`
+}
+
+// getKedaExtension decodes x-kubepose.keda, returning nil when it is unset.
+func getKedaExtension(service types.ServiceConfig) (*kedaExtension, error) {
+	ext, err := getServiceExtension(service)
+	if err != nil || ext.KEDA == nil {
+		return nil, err
+	}
+	var k kedaExtension
+	if err := decodeKubernetesValue(ext.KEDA, &k); err != nil {
+		return nil, fmt.Errorf("invalid %s.keda: %w", ServiceExtensionKey, err)
+	}
+	return &k, nil
+}
+
+// hasKeda reports whether the service is scaled by KEDA.
+func hasKeda(service types.ServiceConfig) bool {
+	k, _ := getKedaExtension(service)
+	return k != nil
+}
+
+// isRunOnce reports whether a service converts to a standalone Pod in
+// Convert, and so to a ScaledJob when scaled by KEDA.
+func isRunOnce(service types.ServiceConfig) bool {
+	_, isCronJob := service.Annotations[CronJobScheduleAnnotationKey]
+	return !isCronJob && getRestartPolicy(service) != corev1.RestartPolicyAlways
+}
+
+// validateKeda rejects KEDA settings that could not produce a working
+// ScaledObject or ScaledJob. Called from validateService.
+func validateKeda(service types.ServiceConfig) error {
+	k, err := getKedaExtension(service)
+	if err != nil || k == nil {
+		return err
+	}
+	key := ServiceExtensionKey + ".keda"
+
+	// KEDA drives the workload through its own HPA; a second one would
+	// fight it over the replica count.
+	if hasHorizontalPodAutoscaler(service) {
+		return fmt.Errorf("%s cannot be combined with %s", key, HpaMaxReplicasAnnotationKey)
+	}
+	if _, isCronJob := service.Annotations[CronJobScheduleAnnotationKey]; isCronJob {
+		return fmt.Errorf("%s cannot be combined with %s", key, CronJobScheduleAnnotationKey)
+	}
+	if service.Deploy != nil && service.Deploy.Mode == "global" {
+		return fmt.Errorf("%s cannot be used with deploy.mode: global", key)
+	}
+	if service.Annotations[ContainerTypeAnnotationKey] == "init" {
+		return fmt.Errorf("%s has no effect on an init container service", key)
+	}
+
+	if len(k.Triggers) == 0 {
+		return fmt.Errorf("%s requires at least one trigger", key)
+	}
+	for i, trigger := range k.Triggers {
+		if trigger.Type == "" {
+			return fmt.Errorf("%s trigger %d: type is required", key, i)
+		}
+	}
+	for name, value := range map[string]*int32{
+		"minReplicaCount":            k.MinReplicaCount,
+		"idleReplicaCount":           k.IdleReplicaCount,
+		"cooldownPeriod":             k.CooldownPeriod,
+		"successfulJobsHistoryLimit": k.SuccessfulJobsHistoryLimit,
+		"failedJobsHistoryLimit":     k.FailedJobsHistoryLimit,
+	} {
+		if value != nil && *value < 0 {
+			return fmt.Errorf("%s.%s must not be negative, got %d", key, name, *value)
+		}
+	}
+	for name, value := range map[string]*int32{
+		"maxReplicaCount": k.MaxReplicaCount,
+		"pollingInterval": k.PollingInterval,
+	} {
+		if value != nil && *value < 1 {
+			return fmt.Errorf("%s.%s must be positive, got %d", key, name, *value)
+		}
+	}
+	if k.MinReplicaCount != nil && k.MaxReplicaCount != nil && *k.MinReplicaCount > *k.MaxReplicaCount {
+		return fmt.Errorf("%s.minReplicaCount (%d) must not exceed maxReplicaCount (%d)", key, *k.MinReplicaCount, *k.MaxReplicaCount)
+	}
+
+	if isRunOnce(service) {
+		if k.CooldownPeriod != nil || k.IdleReplicaCount != nil {
+			return fmt.Errorf("%s: cooldownPeriod and idleReplicaCount only apply to long-running services (a run-once service becomes a ScaledJob)", key)
+		}
+	} else if k.SuccessfulJobsHistoryLimit != nil || k.FailedJobsHistoryLimit != nil {
+		return fmt.Errorf("%s: job history limits only apply to run-once services (a long-running service becomes a ScaledObject)", key)
+	}
+	return nil
+}
+
+// createScaledObject emits a KEDA ScaledObject targeting the service's
+// Deployment. As with an HPA, the Deployment must be emitted without
+// spec.replicas. minReplicaCount defaults to deploy.replicas like the HPA's
+// floor; without either, KEDA scales to zero when idle.
+func (t Transformer) createScaledObject(resources *Resources, service types.ServiceConfig) {
+	serviceName := getServiceName(service)
+	for _, so := range resources.ScaledObjects {
+		if so.ObjectMeta.Name == serviceName {
+			return
+		}
+	}
+
+	// Values are validated in validateService; decode errors cannot occur here.
+	k, _ := getKedaExtension(service)
+	minReplicaCount := k.MinReplicaCount
+	if minReplicaCount == nil && service.Deploy != nil && service.Deploy.Replicas != nil {
+		minReplicaCount = ptr.To(int32(*service.Deploy.Replicas))
+	}
+
+	resources.ScaledObjects = append(resources.ScaledObjects, &keda.ScaledObject{
+		TypeMeta: metav1.TypeMeta{
+			APIVersion: keda.SchemeGroupVersion.String(),
+			Kind:       "ScaledObject",
+		},
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        serviceName,
+			Annotations: mergeMaps(service.Annotations, t.Annotations),
+			Labels:      mergeMaps(service.Labels, t.Labels),
+		},
+		Spec: keda.ScaledObjectSpec{
+			ScaleTargetRef: &keda.ScaleTarget{
+				APIVersion: "apps/v1",
+				Kind:       "Deployment",
+				Name:       serviceName,
+			},
+			PollingInterval:  k.PollingInterval,
+			CooldownPeriod:   k.CooldownPeriod,
+			IdleReplicaCount: k.IdleReplicaCount,
+			MinReplicaCount:  minReplicaCount,
+			MaxReplicaCount:  k.MaxReplicaCount,
+			Triggers:         k.Triggers,
+		},
+	})
+}
+
+// createScaledJob emits a KEDA ScaledJob in place of a run-once service's
+// standalone Pod, running the pod spec as a Job per batch of events.
+func (t Transformer) createScaledJob(resources *Resources, service types.ServiceConfig, podSpec corev1.PodSpec) {
+	// Values are validated in validateService; decode errors cannot occur here.
+	k, _ := getKedaExtension(service)
+
+	resources.ScaledJobs = append(resources.ScaledJobs, &keda.ScaledJob{
+		TypeMeta: metav1.TypeMeta{
+			APIVersion: keda.SchemeGroupVersion.String(),
+			Kind:       "ScaledJob",
+		},
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        service.Name,
+			Annotations: mergeMaps(service.Annotations, t.Annotations),
+			Labels:      mergeMaps(service.Labels, t.Labels),
+		},
+		Spec: keda.ScaledJobSpec{
+			JobTargetRef: &batchv1.JobSpec{
+				Template: corev1.PodTemplateSpec{
+					ObjectMeta: metav1.ObjectMeta{
+						Annotations: mergeMaps(service.Annotations, t.Annotations),
+						Labels: mergeMaps(service.Labels, map[string]string{
+							AppSelectorLabelKey: service.Name,
+						}),
+					},
+					Spec: podSpec,
+				},
+			},
+			PollingInterval:            k.PollingInterval,
+			SuccessfulJobsHistoryLimit: k.SuccessfulJobsHistoryLimit,
+			FailedJobsHistoryLimit:     k.FailedJobsHistoryLimit,
+			MinReplicaCount:            k.MinReplicaCount,
+			MaxReplicaCount:            k.MaxReplicaCount,
+			Triggers:                   k.Triggers,
+		},
+	})
+}
@@ -0,0 +1,42 @@
+package kubepose
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// kubeVersion is a Kubernetes minor release, as targeted by
+// Transformer.KubeVersion.
+type kubeVersion struct {
+	Major, Minor int
+}
+
+// parseKubeVersion parses "1.34", "v1.34" or "v1.34.2"; the patch release is
+// ignored since API fields only change between minor releases.
+func parseKubeVersion(value string) (kubeVersion, error) {
+	parts := strings.SplitN(strings.TrimPrefix(value, "v"), ".", 3)
+	if len(parts) < 2 {
+		return kubeVersion{}, fmt.Errorf("invalid Kubernetes version %q: expected \"major.minor\"", value)
+	}
+	major, err := strconv.Atoi(parts[0])
+	if err != nil || major < 1 {
+		return kubeVersion{}, fmt.Errorf("invalid Kubernetes version %q: expected \"major.minor\"", value)
+	}
+	minor, err := strconv.Atoi(parts[1])
+	if err != nil || minor < 0 {
+		return kubeVersion{}, fmt.Errorf("invalid Kubernetes version %q: expected \"major.minor\"", value)
+	}
+	return kubeVersion{Major: major, Minor: minor}, nil
+}
+
+func (v kubeVersion) Less(other kubeVersion) bool {
+	if v.Major != other.Major {
+		return v.Major < other.Major
+	}
+	return v.Minor < other.Minor
+}
+
+func (v kubeVersion) String() string {
+	return fmt.Sprintf("%d.%d", v.Major, v.Minor)
+}
@@ -0,0 +1,237 @@
+package kubepose
+
+import (
+	"fmt"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/compose-spec/compose-go/v2/types"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/utils/ptr"
+)
+
+// defaultTerminationGracePeriodSeconds is what Kubernetes applies when
+// terminationGracePeriodSeconds is unset.
+const defaultTerminationGracePeriodSeconds = 30
+
+// minStopSignalKubeVersion is the oldest --kube-version target for which the
+// native container lifecycle.stopSignal field is emitted (ContainerStopSignals).
+// Older or unknown targets get a preStop handler that signals PID 1 instead.
+var minStopSignalKubeVersion = kubeVersion{Major: 1, Minor: 34}
+
+// validateLifecycleHooks rejects post_start and pre_stop hooks that cannot
+// become container lifecycle handlers. Handlers exec inside the service
+// container, so hook fields that would need a separate process context
+// (another image, user, privileges, working directory or environment) have
+// no Kubernetes equivalent. Called from validateService.
+func validateLifecycleHooks(service types.ServiceConfig) error {
+	for _, hooks := range []struct {
+		key   string
+		hooks []types.ServiceHook
+	}{
+		{"post_start", service.PostStart},
+		{"pre_stop", service.PreStop},
+	} {
+		if len(hooks.hooks) > 1 {
+			return fmt.Errorf("%s: a container has a single Kubernetes lifecycle handler, got %d hooks; combine them into one command", hooks.key, len(hooks.hooks))
+		}
+		for _, hook := range hooks.hooks {
+			switch {
+			case len(hook.Command) == 0:
+				return fmt.Errorf("%s: command is required", hooks.key)
+			case hook.Image != "":
+				return fmt.Errorf("%s: image is not supported, the hook runs in the service container", hooks.key)
+			case hook.User != "":
+				return fmt.Errorf("%s: user is not supported, the hook runs as the container user", hooks.key)
+			case hook.Privileged:
+				return fmt.Errorf("%s: privileged is not supported, the hook runs with the container's privileges", hooks.key)
+			case hook.WorkingDir != "":
+				return fmt.Errorf("%s: working_dir is not supported, the hook runs in the container's working directory", hooks.key)
+			case len(hook.Environment) > 0:
+				return fmt.Errorf("%s: environment is not supported, the hook sees the container environment", hooks.key)
+			}
+		}
+	}
+
+	value, ok := service.Annotations[LifecyclePreStopSleepAnnotationKey]
+	if !ok {
+		return nil
+	}
+	if _, err := parseSleepSeconds(value); err != nil {
+		return fmt.Errorf("%s: %w", LifecyclePreStopSleepAnnotationKey, err)
+	}
+	if len(service.PreStop) > 0 {
+		return fmt.Errorf("%s cannot be combined with pre_stop: a container has a single preStop handler", LifecyclePreStopSleepAnnotationKey)
+	}
+	return nil
+}
+
+// getPreStopSleepSeconds returns the kubepose.lifecycle.preStop.sleep
+// duration in seconds, or 0 when unset.
+func getPreStopSleepSeconds(service types.ServiceConfig) int64 {
+	value, ok := service.Annotations[LifecyclePreStopSleepAnnotationKey]
+	if !ok {
+		return 0
+	}
+	// validated in validateService; parse errors cannot occur here
+	seconds, _ := parseSleepSeconds(value)
+	return seconds
+}
+
+// parseSleepSeconds parses a duration ("5s", "1m") or a plain number of
+// seconds, rounding sub-second remainders up like stop_grace_period.
+func parseSleepSeconds(value string) (int64, error) {
+	if seconds, err := strconv.ParseInt(value, 10, 64); err == nil {
+		if seconds < 1 {
+			return 0, fmt.Errorf("must be a positive duration, got %q", value)
+		}
+		return seconds, nil
+	}
+	d, err := time.ParseDuration(value)
+	if err != nil || d <= 0 {
+		return 0, fmt.Errorf("must be a positive duration such as \"5s\", got %q", value)
+	}
+	return int64(math.Ceil(d.Seconds())), nil
+}
+
+// getLifecycle converts post_start and pre_stop hooks into exec lifecycle
+// handlers, kubepose.lifecycle.preStop.sleep into the native sleep action,
+// and stop_signal into the container stopSignal or, on older clusters, a
+// preStop handler delivering it. Exec handlers do not go through Kubernetes
+// $(VAR) expansion, so hook commands are passed as is.
+func (t Transformer) getLifecycle(service types.ServiceConfig) *corev1.Lifecycle {
+	lifecycle := &corev1.Lifecycle{}
+	if len(service.PostStart) > 0 {
+		lifecycle.PostStart = &corev1.LifecycleHandler{
+			Exec: &corev1.ExecAction{Command: service.PostStart[0].Command},
+		}
+	}
+	if len(service.PreStop) > 0 {
+		lifecycle.PreStop = &corev1.LifecycleHandler{
+			Exec: &corev1.ExecAction{Command: service.PreStop[0].Command},
+		}
+	}
+	if seconds := getPreStopSleepSeconds(service); seconds > 0 {
+		lifecycle.PreStop = &corev1.LifecycleHandler{
+			Sleep: &corev1.SleepAction{Seconds: seconds},
+		}
+	}
+	if signal := getStopSignal(service); signal != "" {
+		if t.supportsStopSignal() {
+			lifecycle.StopSignal = ptr.To(signal)
+		} else {
+			// The kubelet sends SIGTERM once preStop returns, so the handler
+			// waits for PID 1 to exit. The handler starts the grace period
+			// by signalling, so the grace period bounds the shutdown as
+			// stop_grace_period does in compose.
+			lifecycle.PreStop = &corev1.LifecycleHandler{
+				Exec: &corev1.ExecAction{Command: []string{
+					"/bin/sh", "-c",
+					fmt.Sprintf("kill -s %s 1; while kill -0 1 2>/dev/null; do sleep 1; done", strings.TrimPrefix(string(signal), "SIG")),
+				}},
+			}
+		}
+	}
+	if lifecycle.PostStart == nil && lifecycle.PreStop == nil && lifecycle.StopSignal == nil {
+		return nil
+	}
+	return lifecycle
+}
+
+var reStopSignal = regexp.MustCompile(`^SIG[A-Z]+[0-9]*([+-][0-9]+)?$`)
+
+// getStopSignal normalizes compose stop_signal ("SIGQUIT" or "QUIT") to a
+// Kubernetes signal name. SIGTERM is what Kubernetes sends anyway, so it
+// yields "".
+func getStopSignal(service types.ServiceConfig) corev1.Signal {
+	signal := strings.ToUpper(service.StopSignal)
+	if signal == "" {
+		return ""
+	}
+	if !strings.HasPrefix(signal, "SIG") {
+		signal = "SIG" + signal
+	}
+	if signal == string(corev1.SIGTERM) {
+		return ""
+	}
+	return corev1.Signal(signal)
+}
+
+// validateStopSignal rejects stop_signal values Kubernetes cannot deliver
+// and, on clusters without native stop signals, combinations with another
+// preStop handler. Called from Convert, since the cluster version lives on
+// the Transformer rather than the service.
+func (t Transformer) validateStopSignal(service types.ServiceConfig) error {
+	if service.StopSignal == "" {
+		return nil
+	}
+	signal := getStopSignal(service)
+	if signal == "" {
+		return nil
+	}
+	if !reStopSignal.MatchString(string(signal)) {
+		return fmt.Errorf("stop_signal %q: only signal names such as SIGQUIT are supported", service.StopSignal)
+	}
+	if t.supportsStopSignal() {
+		return nil
+	}
+	if len(service.PreStop) > 0 || getPreStopSleepSeconds(service) > 0 {
+		return fmt.Errorf("stop_signal %q is delivered by a preStop handler before Kubernetes %s, which cannot be combined with pre_stop or %s; target a newer cluster with --kube-version", service.StopSignal, minStopSignalKubeVersion, LifecyclePreStopSleepAnnotationKey)
+	}
+	return nil
+}
+
+// validateStopSignalPID rejects the preStop stop signal fallback in pods
+// whose PID 1 is not the app: with pid: service:<name> the pod shares one
+// process namespace and PID 1 is the pause container, and with pid: host it
+// is the node's init. Called from Convert once pod groups are known.
+func (t Transformer) validateStopSignalPID(project *types.Project) error {
+	if t.supportsStopSignal() {
+		return nil
+	}
+	pids := map[string]string{}
+	for _, name := range project.ServiceNames() {
+		service := project.Services[name]
+		if service.Pid != "" {
+			pids[getServiceName(service)] = service.Pid
+		}
+	}
+	for _, name := range project.ServiceNames() {
+		service := project.Services[name]
+		if getStopSignal(service) == "" {
+			continue
+		}
+		if pid, ok := pids[getServiceName(service)]; ok {
+			return fmt.Errorf("service %q: stop_signal %q is delivered by a preStop handler signalling PID 1 before Kubernetes %s, which is not the service's process with pid %q in its pod; target a newer cluster with --kube-version", name, service.StopSignal, minStopSignalKubeVersion, pid)
+		}
+	}
+	return nil
+}
+
+// supportsStopSignal reports whether the target cluster honours the native
+// container stopSignal field.
+func (t Transformer) supportsStopSignal() bool {
+	if t.KubeVersion == "" {
+		return false
+	}
+	// validated in Convert; parse errors cannot occur here
+	version, _ := parseKubeVersion(t.KubeVersion)
+	return !version.Less(minStopSignalKubeVersion)
+}
+
+// setPodOSForStopSignals sets the pod OS to linux when a container declares a
+// stopSignal, which Kubernetes only accepts on pods with spec.os.name set.
+// compose signal names are Linux signals, so linux is the only choice.
+func setPodOSForStopSignals(podSpec *corev1.PodSpec) {
+	for _, containers := range [][]corev1.Container{podSpec.InitContainers, podSpec.Containers} {
+		for _, container := range containers {
+			if container.Lifecycle != nil && container.Lifecycle.StopSignal != nil {
+				podSpec.OS = &corev1.PodOS{Name: corev1.Linux}
+				return
+			}
+		}
+	}
+}
@@ -0,0 +1,211 @@
+package kubepose
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/compose-spec/compose-go/v2/types"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/utils/ptr"
+)
+
+// namespaceSetting is a compose option that shares a Linux namespace with the
+// host or another service.
+type namespaceSetting struct {
+	key   string
+	value func(types.ServiceConfig) string
+}
+
+var namespaceSettings = []namespaceSetting{
+	{"network_mode", func(s types.ServiceConfig) string { return s.NetworkMode }},
+	{"ipc", func(s types.ServiceConfig) string { return s.Ipc }},
+	{"pid", func(s types.ServiceConfig) string { return s.Pid }},
+}
+
+// validateNamespaces rejects network_mode, ipc and pid values a pod cannot
+// represent. Called from validateService; "service:<name>" references are
+// resolved by groupSharedNamespaces, which needs the whole project.
+func validateNamespaces(service types.ServiceConfig) error {
+	switch mode := service.NetworkMode; {
+	case mode == "", mode == "bridge", mode == "default", mode == "host":
+	case strings.HasPrefix(mode, types.ServicePrefix):
+	default:
+		return fmt.Errorf(`network_mode %q: only "host", "service:<name>" or the default network are supported`, mode)
+	}
+	switch mode := service.Ipc; {
+	case mode == "", mode == "private", mode == "shareable", mode == "host":
+	case strings.HasPrefix(mode, types.ServicePrefix):
+	default:
+		return fmt.Errorf(`ipc %q: only "private", "shareable", "host" or "service:<name>" are supported`, mode)
+	}
+	switch mode := service.Pid; {
+	case mode == "", mode == "host":
+	case strings.HasPrefix(mode, types.ServicePrefix):
+	default:
+		return fmt.Errorf(`pid %q: only "host" or "service:<name>" are supported`, mode)
+	}
+	return nil
+}
+
+// groupSharedNamespaces returns a shallow copy of the project in which every
+// service sharing a namespace with another through "service:<name>" is put in
+// the same kubepose.service.group, since containers can only share network,
+// IPC or process namespaces inside one pod. Services joined this way adopt an
+// explicit group of any member, otherwise the name of the service the others
+// attach to. The caller's project is left untouched.
+func groupSharedNamespaces(project *types.Project) (*types.Project, error) {
+	names := project.ServiceNames()
+
+	parent := make(map[string]string, len(names))
+	var find func(string) string
+	find = func(name string) string {
+		if p, ok := parent[name]; ok && p != name {
+			root := find(p)
+			parent[name] = root
+			return root
+		}
+		return name
+	}
+
+	// Services that attach to no other service are preferred as the group
+	// name: they are what compose starts first.
+	attaches := make(map[string]bool)
+	for _, name := range names {
+		service := project.Services[name]
+		for _, setting := range namespaceSettings {
+			value := setting.value(service)
+			target, ok := strings.CutPrefix(value, types.ServicePrefix)
+			if !ok {
+				continue
+			}
+			other, exists := project.Services[target]
+			if !exists {
+				return nil, fmt.Errorf("service %q: %s %q: service %q not found", name, setting.key, value, target)
+			}
+			if target == name {
+				return nil, fmt.Errorf("service %q: %s %q: a service cannot share a namespace with itself", name, setting.key, value)
+			}
+			for _, s := range []types.ServiceConfig{service, other} {
+				if !isPodGroupMember(s) {
+					return nil, fmt.Errorf("service %q: %s %q: service %q does not run in a shared pod (it converts to a standalone Pod or an ExternalName Service)", name, setting.key, value, s.Name)
+				}
+			}
+			attaches[name] = true
+			parent[find(name)] = find(target)
+		}
+	}
+
+	components := make(map[string][]string)
+	for _, name := range names {
+		root := find(name)
+		components[root] = append(components[root], name)
+	}
+
+	grouped := *project
+	grouped.Services = make(types.Services, len(project.Services))
+	for name, service := range project.Services {
+		grouped.Services[name] = service
+	}
+	for _, members := range components {
+		if len(members) < 2 {
+			continue
+		}
+
+		var groupName, explicitFrom string
+		for _, name := range members {
+			group := project.Services[name].Annotations[ServiceGroupAnnotationKey]
+			if group == "" {
+				continue
+			}
+			if groupName != "" && group != groupName {
+				return nil, fmt.Errorf("service %q: %s %q conflicts with %q on service %q, which shares a namespace with it", name, ServiceGroupAnnotationKey, group, groupName, explicitFrom)
+			}
+			groupName, explicitFrom = group, name
+		}
+		if groupName == "" {
+			groupName = members[0]
+			for _, name := range members {
+				if !attaches[name] {
+					groupName = name
+					break
+				}
+			}
+		}
+
+		for _, name := range members {
+			service := grouped.Services[name]
+			service.Annotations = mergeMaps(service.Annotations, map[string]string{
+				ServiceGroupAnnotationKey: groupName,
+			})
+			grouped.Services[name] = service
+		}
+	}
+
+	if err := validateHostNamespaces(&grouped); err != nil {
+		return nil, err
+	}
+	return &grouped, nil
+}
+
+// validateHostNamespaces rejects pods whose members disagree on sharing a host
+// namespace: the setting applies to the whole pod, so one member's
+// network_mode: host would silently move its group mates onto the host
+// network too. Members attached to another service inherit its namespace and
+// are not compared.
+func validateHostNamespaces(project *types.Project) error {
+	groups := make(map[string][]types.ServiceConfig)
+	for _, name := range project.ServiceNames() {
+		service := project.Services[name]
+		if isPodGroupMember(service) {
+			groups[getServiceName(service)] = append(groups[getServiceName(service)], service)
+		}
+	}
+	for groupName, members := range groups {
+		for _, setting := range namespaceSettings {
+			var first *types.ServiceConfig
+			for i, service := range members {
+				if strings.HasPrefix(setting.value(service), types.ServicePrefix) {
+					continue
+				}
+				if first == nil {
+					first = &members[i]
+					continue
+				}
+				if (setting.value(service) == "host") != (setting.value(*first) == "host") {
+					return fmt.Errorf("group %q: %s of service %q (%q) conflicts with service %q (%q); a pod either shares the host namespace or not", groupName, setting.key, service.Name, setting.value(service), first.Name, setting.value(*first))
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// isPodGroupMember reports whether a service converts to a workload whose pod
+// can hold other services' containers, mirroring the branches in Convert.
+func isPodGroupMember(service types.ServiceConfig) bool {
+	if getServiceType(service) == corev1.ServiceTypeExternalName {
+		return false
+	}
+	if _, isCronJob := service.Annotations[CronJobScheduleAnnotationKey]; isCronJob {
+		return true
+	}
+	return getRestartPolicy(service) == corev1.RestartPolicyAlways
+}
+
+// mergePodNamespaces folds a service's host namespace and process sharing
+// settings into the pod spec. Conflicts between members are rejected by
+// validateHostNamespaces, so any member asking for a namespace enables it.
+func mergePodNamespaces(podSpec *corev1.PodSpec, service types.ServiceConfig) {
+	if service.NetworkMode == "host" {
+		podSpec.HostNetwork = true
+	}
+	if service.Ipc == "host" {
+		podSpec.HostIPC = true
+	}
+	if service.Pid == "host" {
+		podSpec.HostPID = true
+	}
+	if strings.HasPrefix(service.Pid, types.ServicePrefix) {
+		podSpec.ShareProcessNamespace = ptr.To(true)
+	}
+}
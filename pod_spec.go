@@ -16,13 +16,22 @@ import (
 )
 
 func (t Transformer) createPodSpec(service types.ServiceConfig) corev1.PodSpec {
+	dnsPolicy, dnsConfig := getDNSConfig(service)
 	return corev1.PodSpec{
 		RestartPolicy:                 getRestartPolicy(service),
+		DNSPolicy:                     dnsPolicy,
+		DNSConfig:                     dnsConfig,
+		Hostname:                      service.Hostname,
+		Subdomain:                     service.DomainName,
+		HostNetwork:                   service.NetworkMode == "host",
+		HostIPC:                       service.Ipc == "host",
+		HostPID:                       service.Pid == "host",
 		SecurityContext:               getSecurityContext(service),
 		ServiceAccountName:            service.Annotations[ServiceAccountNameAnnotationKey],
 		TopologySpreadConstraints:     getTopologySpreadConstraints(service),
 		HostAliases:                   convertExtraHosts(service.ExtraHosts),
 		TerminationGracePeriodSeconds: getTerminationGracePeriodSeconds(service),
+		ImagePullSecrets:              t.getImagePullSecrets(service),
 	}
 }
 
@@ -108,7 +117,9 @@ func getSecurityContext(service types.ServiceConfig) *corev1.PodSecurityContext
 		}
 	}
 
-	if runAsUser == nil && runAsGroup == nil && fsGroup == nil && len(supplementalGroups) == 0 {
+	sysctls := getSysctls(service)
+
+	if runAsUser == nil && runAsGroup == nil && fsGroup == nil && len(supplementalGroups) == 0 && len(sysctls) == 0 {
 		return nil
 	}
 
@@ -117,6 +128,7 @@ func getSecurityContext(service types.ServiceConfig) *corev1.PodSecurityContext
 		RunAsGroup:         runAsGroup,
 		FSGroup:            fsGroup,
 		SupplementalGroups: supplementalGroups,
+		Sysctls:            sysctls,
 	}
 }
 
@@ -205,15 +217,23 @@ func getMatchLabels(service types.ServiceConfig) map[string]string {
 	return matchLabels
 }
 
+// getTerminationGracePeriodSeconds converts stop_grace_period, the time
+// compose allows between the stop signal and SIGKILL. Kubernetes counts a
+// preStop handler against the same budget before the signal is sent, so a
+// kubepose.lifecycle.preStop.sleep is added on top to keep the service's
+// full grace period after the signal. The stop_signal preStop fallback needs
+// no adjustment: it sends the signal as soon as the pod terminates and then
+// spends the grace period waiting, just as compose would.
 func getTerminationGracePeriodSeconds(service types.ServiceConfig) *int64 {
-	if service.StopGracePeriod == nil {
-		return nil
+	sleep := getPreStopSleepSeconds(service)
+	if service.StopGracePeriod == nil || time.Duration(*service.StopGracePeriod) < 0 {
+		if sleep == 0 {
+			return nil
+		}
+		return ptr.To(defaultTerminationGracePeriodSeconds + sleep)
 	}
 	d := time.Duration(*service.StopGracePeriod)
-	if d < 0 {
-		return nil
-	}
 	// Ceil to whole seconds so any non-zero grace stays non-zero on Kubernetes.
-	seconds := int64((d + time.Second - 1) / time.Second)
+	seconds := int64((d+time.Second-1)/time.Second) + sleep
 	return &seconds
 }
@@ -0,0 +1,221 @@
+package kubepose
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/compose-spec/compose-go/v2/types"
+	"github.com/sirupsen/logrus"
+	"k8s.io/apimachinery/pkg/util/validation"
+)
+
+// maxPortRangeSize caps how many ports a single compose range may expand to.
+// Every port becomes its own container and Service port, and large ranges
+// (typical for RTP or passive FTP) would produce unwieldy Services.
+const maxPortRangeSize = 100
+
+// parsePortRange parses "port" or "start-end" into an inclusive range.
+func parsePortRange(value string) (int, int, error) {
+	startValue, endValue, isRange := strings.Cut(value, "-")
+	start, err := strconv.Atoi(startValue)
+	if err != nil || start < 1 || start > 65535 {
+		return 0, 0, fmt.Errorf("invalid port %q", value)
+	}
+	if !isRange {
+		return start, start, nil
+	}
+	end, err := strconv.Atoi(endValue)
+	if err != nil || end < start || end > 65535 {
+		return 0, 0, fmt.Errorf("invalid port range %q", value)
+	}
+	if end-start+1 > maxPortRangeSize {
+		return 0, 0, fmt.Errorf("port range %q expands to %d ports, more than the limit of %d", value, end-start+1, maxPortRangeSize)
+	}
+	return start, end, nil
+}
+
+// portRun is a run of consecutive port entries, service.Ports[first:first+n].
+type portRun struct {
+	first, n int
+}
+
+// consecutivePortRuns finds the ranges compose has already expanded:
+// compose-go turns "8000-8010:8000-8010" into single port entries, so such a
+// range only shows up as adjacent unnamed entries whose target and published
+// ports both count up by one.
+func consecutivePortRuns(ports []types.ServicePortConfig) []portRun {
+	next := func(prev, port types.ServicePortConfig) bool {
+		if prev.Name != "" || port.Name != "" || port.Target != prev.Target+1 ||
+			port.Protocol != prev.Protocol || port.Mode != prev.Mode || port.HostIP != prev.HostIP {
+			return false
+		}
+		if prev.Published == "" || port.Published == "" {
+			return prev.Published == port.Published
+		}
+		prevPublished, err1 := strconv.Atoi(prev.Published)
+		published, err2 := strconv.Atoi(port.Published)
+		return err1 == nil && err2 == nil && published == prevPublished+1
+	}
+	var runs []portRun
+	for i := 0; i < len(ports); {
+		n := 1
+		for i+n < len(ports) && next(ports[i+n-1], ports[i+n]) {
+			n++
+		}
+		if n > 1 {
+			runs = append(runs, portRun{first: i, n: n})
+		}
+		i += n
+	}
+	return runs
+}
+
+// validatePorts rejects port and expose entries that cannot be expanded into
+// individual Kubernetes ports. Called from validateService.
+func validatePorts(service types.ServiceConfig) error {
+	names := make(map[string]bool)
+	for _, port := range service.Ports {
+		if port.Published != "" {
+			start, end, err := parsePortRange(port.Published)
+			if err != nil {
+				return fmt.Errorf("invalid published port %q: %w", port.Published, err)
+			}
+			if port.Name != "" && start != end {
+				return fmt.Errorf("port %q: a named port cannot publish a range, since port names must be unique", port.Name)
+			}
+		}
+		if port.Name != "" {
+			if errs := validation.IsValidPortName(port.Name); len(errs) > 0 {
+				return fmt.Errorf("invalid port name %q: %s", port.Name, strings.Join(errs, ", "))
+			}
+			if names[port.Name] {
+				return fmt.Errorf("duplicate port name %q", port.Name)
+			}
+			names[port.Name] = true
+		}
+	}
+	for _, run := range consecutivePortRuns(service.Ports) {
+		if run.n > maxPortRangeSize {
+			first, last := service.Ports[run.first], service.Ports[run.first+run.n-1]
+			return fmt.Errorf("ports %d-%d are %d consecutive ports, more than the limit of %d for a port range (compose expands ranges such as \"%d-%d:%d-%d\" into single ports)", first.Target, last.Target, run.n, maxPortRangeSize, first.Target, last.Target, first.Target, last.Target)
+		}
+	}
+	for _, e := range service.Expose {
+		target, _, _ := strings.Cut(e, "/")
+		if _, _, err := parsePortRange(target); err != nil {
+			return fmt.Errorf(`invalid expose entry %q: only "port", "start-end" or either with "/protocol" is supported: %w`, e, err)
+		}
+	}
+	if want, ok := service.Annotations[ServiceExposePortAnnotationKey]; ok && !hasTCPPort(service, want) {
+		return fmt.Errorf("%s %q does not match the name or number of a TCP port", ServiceExposePortAnnotationKey, want)
+	}
+	if value, ok := service.Annotations[HealthcheckHttpGetPortAnnotationKey]; ok {
+		if _, err := strconv.Atoi(value); err != nil {
+			if _, ok := findPortByName(service, value); !ok {
+				return fmt.Errorf("%s %q is neither a port number nor the name of a declared port", HealthcheckHttpGetPortAnnotationKey, value)
+			}
+		}
+	}
+	return nil
+}
+
+// hasTCPPort reports whether a TCP port or expose entry is named or numbered
+// want. Runs before range expansion, so numbers are matched against ranges.
+func hasTCPPort(service types.ServiceConfig, want string) bool {
+	isTCP := func(protocol string) bool { return protocol == "" || strings.EqualFold(protocol, "tcp") }
+	n, _ := strconv.Atoi(want)
+	for _, port := range service.Ports {
+		if !isTCP(port.Protocol) {
+			continue
+		}
+		if port.Name != "" && port.Name == want {
+			return true
+		}
+		start, end := int(port.Target), int(port.Target)
+		if port.Published != "" {
+			start, end, _ = parsePortRange(port.Published)
+		}
+		if n >= start && n <= end && n > 0 {
+			return true
+		}
+	}
+	for _, e := range service.Expose {
+		target, protocol, _ := strings.Cut(e, "/")
+		if !isTCP(protocol) {
+			continue
+		}
+		start, end, _ := parsePortRange(target)
+		if n >= start && n <= end && n > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// expandPortRanges returns a shallow copy of the project in which every
+// published port range and expose range is replaced by individual ports, so
+// the rest of the conversion only deals with single ports. The caller's
+// project is left untouched. Entries are validated in validateService.
+func expandPortRanges(project *types.Project) *types.Project {
+	expanded := *project
+	expanded.Services = make(types.Services, len(project.Services))
+	for name, service := range project.Services {
+		for _, run := range consecutivePortRuns(service.Ports) {
+			first, last := service.Ports[run.first], service.Ports[run.first+run.n-1]
+			logrus.Warnf("service %q: consecutive ports %d-%d, as from an expanded port range, become %d Service ports", name, first.Target, last.Target, run.n)
+		}
+
+		var ports []types.ServicePortConfig
+		for _, port := range service.Ports {
+			start, end, _ := parsePortRange(port.Published)
+			if port.Published == "" || start == end {
+				ports = append(ports, port)
+				continue
+			}
+			// compose only leaves a published range unexpanded when it maps
+			// to a single container port: the host binds any port of the
+			// range. Kubernetes cannot pick one, so every port of the range
+			// is forwarded to the container port.
+			logrus.Warnf("service %q: expanding published port range %s into %d Service ports", name, port.Published, end-start+1)
+			for p := start; p <= end; p++ {
+				single := port
+				single.Published = strconv.Itoa(p)
+				ports = append(ports, single)
+			}
+		}
+		service.Ports = ports
+
+		var expose types.StringOrNumberList
+		for _, e := range service.Expose {
+			target, protocol, hasProtocol := strings.Cut(e, "/")
+			start, end, _ := parsePortRange(target)
+			if start == end {
+				expose = append(expose, e)
+				continue
+			}
+			logrus.Warnf("service %q: expanding expose range %s into %d Service ports", name, target, end-start+1)
+			for p := start; p <= end; p++ {
+				entry := strconv.Itoa(p)
+				if hasProtocol {
+					entry += "/" + protocol
+				}
+				expose = append(expose, entry)
+			}
+		}
+		service.Expose = expose
+
+		expanded.Services[name] = service
+	}
+	return &expanded
+}
+
+// findPortByName returns the compose port declaring the given name.
+func findPortByName(service types.ServiceConfig, name string) (types.ServicePortConfig, bool) {
+	for _, port := range service.Ports {
+		if port.Name != "" && port.Name == name {
+			return port, true
+		}
+	}
+	return types.ServicePortConfig{}, false
+}
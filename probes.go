@@ -82,10 +82,14 @@ func getProbes(service types.ServiceConfig) (liveness *corev1.Probe, readiness *
 
 	// Check for HTTP-specific health check annotations
 	if path, ok := service.Annotations[HealthcheckHttpGetPathAnnotationKey]; ok {
-		httpGetPort := getFirstPort(service)
+		httpGetPort := intstr.FromInt(getFirstPort(service))
 		if port, ok := service.Annotations[HealthcheckHttpGetPortAnnotationKey]; ok {
 			if p, err := strconv.Atoi(port); err == nil {
-				httpGetPort = p
+				httpGetPort = intstr.FromInt(p)
+			} else {
+				// A non-numeric value names a container port; validatePorts
+				// guarantees it is declared.
+				httpGetPort = intstr.FromString(port)
 			}
 		}
 
@@ -93,7 +97,7 @@ func getProbes(service types.ServiceConfig) (liveness *corev1.Probe, readiness *
 			ProbeHandler: corev1.ProbeHandler{
 				HTTPGet: &corev1.HTTPGetAction{
 					Path: path,
-					Port: intstr.FromInt(httpGetPort),
+					Port: httpGetPort,
 				},
 			},
 		}
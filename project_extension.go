@@ -0,0 +1,165 @@
+package kubepose
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/compose-spec/compose-go/v2/types"
+	"k8s.io/apimachinery/pkg/util/validation"
+)
+
+// Output formats of Resources.Write.
+const (
+	OutputYAML = "yaml"
+	OutputJSON = "json"
+)
+
+// projectExtension is the top-level x-kubepose extension: project-wide
+// defaults that would otherwise be repeated on every service and volume.
+type projectExtension struct {
+	// Namespace is set on every generated object.
+	Namespace string `json:"namespace,omitempty"`
+	// CommonLabels are added to every generated object, like the labels
+	// given to the Transformer, which take precedence.
+	CommonLabels map[string]string `json:"commonLabels,omitempty"`
+	// Defaults holds kubepose annotations and volume labels applied to
+	// every service and volume that does not set them itself.
+	Defaults struct {
+		Annotations  map[string]string `json:"annotations,omitempty"`
+		VolumeLabels map[string]string `json:"volumeLabels,omitempty"`
+	} `json:"defaults,omitempty"`
+	// Output is the default output format, OutputYAML or OutputJSON.
+	Output string `json:"output,omitempty"`
+}
+
+// serviceAnnotationKeys are the kubepose annotations a service can set, and
+// so the ones x-kubepose.defaults.annotations accepts.
+var serviceAnnotationKeys = []string{
+	ServiceGroupAnnotationKey,
+	ServiceAccountNameAnnotationKey,
+	ServiceExposeAnnotationKey,
+	ServiceExposeIngressClassNameAnnotationKey,
+	ServiceExposeGatewayAnnotationKey,
+	ServiceExposePortAnnotationKey,
+	SelectorMatchLabelsAnnotationKey,
+	HealthcheckHttpGetPathAnnotationKey,
+	HealthcheckHttpGetPortAnnotationKey,
+	ContainerTypeAnnotationKey,
+	ServiceTypeAnnotationKey,
+	ServiceLoadBalancerSourceRangesAnnotationKey,
+	ServiceLoadBalancerClassAnnotationKey,
+	ServiceExternalTrafficPolicyAnnotationKey,
+	LifecyclePreStopSleepAnnotationKey,
+	DependsOnAnnotationKey,
+	CronJobScheduleAnnotationKey,
+	CronJobConcurrencyPolicyAnnotationKey,
+	CronJobTimeZoneAnnotationKey,
+	CronJobSuspendAnnotationKey,
+	CronJobStartingDeadlineSecondsAnnotationKey,
+	CronJobSuccessfulJobsHistoryLimitAnnotationKey,
+	CronJobFailedJobsHistoryLimitAnnotationKey,
+	CronJobBackoffLimitAnnotationKey,
+	CronJobActiveDeadlineSecondsAnnotationKey,
+	HpaMaxReplicasAnnotationKey,
+	HpaMinReplicasAnnotationKey,
+	HpaCpuAnnotationKey,
+	HpaScaleUpStabilizationWindowAnnotationKey,
+	HpaScaleDownStabilizationWindowAnnotationKey,
+	HpaScaleUpPoliciesAnnotationKey,
+	HpaScaleDownPoliciesAnnotationKey,
+	HpaMetricsAnnotationKey,
+	VpaUpdateModeAnnotationKey,
+	GpuResourceNameAnnotationKey,
+	ImagePullSecretsAnnotationKey,
+}
+
+// volumeLabelKeys are the kubepose volume labels that make sense as
+// defaults; a default host path would make every volume share a directory.
+var volumeLabelKeys = []string{
+	VolumeStorageClassNameLabelKey,
+	VolumeSizeLabelKey,
+}
+
+// getProjectExtension decodes and validates the project's top-level
+// x-kubepose extension. A missing extension yields the zero value.
+func getProjectExtension(project *types.Project) (projectExtension, error) {
+	var ext projectExtension
+	value, ok := project.Extensions[ServiceExtensionKey]
+	if !ok {
+		return ext, nil
+	}
+	if err := decodeKubernetesValue(value, &ext); err != nil {
+		return ext, fmt.Errorf("invalid %s extension: %w", ServiceExtensionKey, err)
+	}
+
+	if ext.Namespace != "" {
+		if errs := validation.IsDNS1123Label(ext.Namespace); len(errs) > 0 {
+			return ext, fmt.Errorf("%s.namespace %q: %s", ServiceExtensionKey, ext.Namespace, strings.Join(errs, "; "))
+		}
+	}
+	for _, key := range sortedKeys(ext.CommonLabels) {
+		if errs := validation.IsQualifiedName(key); len(errs) > 0 {
+			return ext, fmt.Errorf("%s.commonLabels %q: %s", ServiceExtensionKey, key, strings.Join(errs, "; "))
+		}
+		if errs := validation.IsValidLabelValue(ext.CommonLabels[key]); len(errs) > 0 {
+			return ext, fmt.Errorf("%s.commonLabels %q: %s", ServiceExtensionKey, key, strings.Join(errs, "; "))
+		}
+	}
+	if err := checkKnownKeys(ext.Defaults.Annotations, serviceAnnotationKeys, "defaults.annotations"); err != nil {
+		return ext, err
+	}
+	if err := checkKnownKeys(ext.Defaults.VolumeLabels, volumeLabelKeys, "defaults.volumeLabels"); err != nil {
+		return ext, err
+	}
+	switch ext.Output {
+	case "", OutputYAML, OutputJSON:
+	default:
+		return ext, fmt.Errorf("%s.output %q: expected %q or %q", ServiceExtensionKey, ext.Output, OutputYAML, OutputJSON)
+	}
+	return ext, nil
+}
+
+// checkKnownKeys rejects keys that kubepose would ignore, so a typo in a
+// project-wide default does not go unnoticed.
+func checkKnownKeys(m map[string]string, known []string, field string) error {
+	for _, key := range sortedKeys(m) {
+		found := false
+		for _, k := range known {
+			if k == key {
+				found = true
+				break
+			}
+		}
+		if !found {
+			sorted := append([]string(nil), known...)
+			sort.Strings(sorted)
+			return fmt.Errorf("%s.%s: unknown key %q (expected one of %s)", ServiceExtensionKey, field, key, strings.Join(sorted, ", "))
+		}
+	}
+	return nil
+}
+
+// applyDefaults returns a copy of project whose services and volumes carry
+// the default annotations and labels they do not set themselves.
+func (ext projectExtension) applyDefaults(project *types.Project) *types.Project {
+	if len(ext.Defaults.Annotations) == 0 && len(ext.Defaults.VolumeLabels) == 0 {
+		return project
+	}
+	copied := *project
+	if len(ext.Defaults.Annotations) > 0 {
+		copied.Services = make(types.Services, len(project.Services))
+		for name, service := range project.Services {
+			service.Annotations = mergeMaps(ext.Defaults.Annotations, service.Annotations)
+			copied.Services[name] = service
+		}
+	}
+	if len(ext.Defaults.VolumeLabels) > 0 {
+		copied.Volumes = make(types.Volumes, len(project.Volumes))
+		for name, volume := range project.Volumes {
+			volume.Labels = mergeMaps(ext.Defaults.VolumeLabels, volume.Labels)
+			copied.Volumes[name] = volume
+		}
+	}
+	return &copied
+}
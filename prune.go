@@ -0,0 +1,147 @@
+package kubepose
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+)
+
+// StaleObject identifies a generated ConfigMap, Secret or SealedSecret that
+// the current conversion no longer produces.
+type StaleObject struct {
+	Kind      string
+	Namespace string
+	Name      string
+}
+
+func (o StaleObject) String() string {
+	if o.Namespace == "" {
+		return fmt.Sprintf("%s/%s", o.Kind, o.Name)
+	}
+	return fmt.Sprintf("%s/%s (namespace %s)", o.Kind, o.Name, o.Namespace)
+}
+
+// liveObject is the part of a `kubectl get -o json` object prune reads.
+type liveObject struct {
+	Kind     string `json:"kind"`
+	Metadata struct {
+		Name      string            `json:"name"`
+		Namespace string            `json:"namespace"`
+		Labels    map[string]string `json:"labels"`
+	} `json:"metadata"`
+	Items []liveObject `json:"items"`
+}
+
+// FindStaleObjects compares the resources of a fresh conversion with a
+// `kubectl get -o json` dump (a List or a single object) and returns the
+// ConfigMaps, Secrets and SealedSecrets generated for the project that the
+// resources no longer contain. Only objects carrying kubepose's ownership labels are
+// considered, so hand-made and external objects are never returned.
+//
+// Only live objects in the target namespace are considered, since the same
+// project may be deployed to several. The target is namespace, else the
+// namespace of the generated objects (x-kubepose.namespace), else the one
+// namespace the dump holds; a dump spanning several namespaces without a
+// target is an error.
+func FindStaleObjects(resources *Resources, projectName, namespace string, live io.Reader) ([]StaleObject, error) {
+	if projectName == "" {
+		return nil, fmt.Errorf("a project name is required to find stale objects")
+	}
+	for _, object := range resources.objects() {
+		generated := object.GetNamespace()
+		if generated == "" {
+			continue
+		}
+		if namespace != "" && namespace != generated {
+			return nil, fmt.Errorf("namespace %q differs from the namespace %q of the generated objects", namespace, generated)
+		}
+		namespace = generated
+		break
+	}
+
+	var root liveObject
+	if err := json.NewDecoder(live).Decode(&root); err != nil {
+		return nil, fmt.Errorf("reading kubectl JSON: %w", err)
+	}
+	// kubectl prints a List (ConfigMapList etc. from the raw API) for
+	// several objects and the object itself when one is named.
+	objects := []liveObject{root}
+	if strings.HasSuffix(root.Kind, "List") {
+		objects = root.Items
+	}
+
+	if namespace == "" {
+		namespaces := map[string]bool{}
+		for _, object := range objects {
+			namespaces[object.Metadata.Namespace] = true
+		}
+		if len(namespaces) > 1 {
+			return nil, fmt.Errorf("the live objects span the namespaces %s; a target namespace is required", strings.Join(sortedKeys(namespaces), ", "))
+		}
+		for ns := range namespaces {
+			namespace = ns
+		}
+	}
+
+	current := map[StaleObject]bool{}
+	for _, cm := range resources.ConfigMaps {
+		current[StaleObject{"ConfigMap", namespace, cm.Name}] = true
+	}
+	// A generated Secret may be applied sealed or not, whatever the prune
+	// run's options, and the controller owns the Secret of a SealedSecret:
+	// both stay current either way.
+	var secretNames []string
+	for _, secret := range resources.Secrets {
+		secretNames = append(secretNames, secret.Name)
+	}
+	for _, sealed := range resources.SealedSecrets {
+		secretNames = append(secretNames, sealed.Name)
+	}
+	for _, secret := range resources.EncryptedSecrets {
+		secretNames = append(secretNames, secret.Name)
+	}
+	for _, name := range secretNames {
+		current[StaleObject{"Secret", namespace, name}] = true
+		current[StaleObject{"SealedSecret", namespace, name}] = true
+	}
+	// The operator owns the target Secrets of ExternalSecrets, which older
+	// kubepose versions labelled like generated ones.
+	for _, es := range resources.ExternalSecrets {
+		current[StaleObject{"Secret", namespace, es.Spec.Target.Name}] = true
+	}
+
+	var stale []StaleObject
+	for _, object := range objects {
+		if object.Metadata.Namespace != "" && object.Metadata.Namespace != namespace {
+			continue
+		}
+		labels := object.Metadata.Labels
+		if labels[ProjectLabelKey] != projectName {
+			continue
+		}
+		var owned bool
+		switch object.Kind {
+		case "ConfigMap":
+			owned = labels[ConfigLabelKey] != "" || labels[VolumeServiceLabelKey] != ""
+		case "Secret", "SealedSecret":
+			owned = labels[SecretLabelKey] != ""
+		}
+		key := StaleObject{Kind: object.Kind, Namespace: namespace, Name: object.Metadata.Name}
+		if !owned || current[key] {
+			continue
+		}
+		stale = append(stale, key)
+	}
+	sort.Slice(stale, func(i, j int) bool {
+		if stale[i].Namespace != stale[j].Namespace {
+			return stale[i].Namespace < stale[j].Namespace
+		}
+		if stale[i].Kind != stale[j].Kind {
+			return stale[i].Kind < stale[j].Kind
+		}
+		return stale[i].Name < stale[j].Name
+	})
+	return stale, nil
+}
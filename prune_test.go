@@ -0,0 +1,173 @@
+package kubepose_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/compose-spec/compose-go/v2/types"
+	"github.com/middle-management/kubepose"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestFindStaleObjects(t *testing.T) {
+	t.Parallel()
+
+	resources := &kubepose.Resources{
+		ConfigMaps: []*corev1.ConfigMap{{ObjectMeta: metav1.ObjectMeta{Name: "app-config-22222222"}}},
+		Secrets:    []*corev1.Secret{{ObjectMeta: metav1.ObjectMeta{Name: "db-password-44444444"}}},
+	}
+	live := `{
+	  "apiVersion": "v1",
+	  "kind": "List",
+	  "items": [
+	    {"kind": "ConfigMap", "metadata": {"name": "app-config-11111111", "namespace": "shop",
+	      "labels": {"kubepose.project": "shop", "kubepose.config": "app-config"}}},
+	    {"kind": "ConfigMap", "metadata": {"name": "app-config-22222222", "namespace": "shop",
+	      "labels": {"kubepose.project": "shop", "kubepose.config": "app-config"}}},
+	    {"kind": "ConfigMap", "metadata": {"name": "web-33333333", "namespace": "shop",
+	      "labels": {"kubepose.project": "shop", "kubepose.volume.service": "web"}}},
+	    {"kind": "Secret", "metadata": {"name": "db-password-55555555", "namespace": "shop",
+	      "labels": {"kubepose.project": "shop", "kubepose.secret": "db-password"}}},
+	    {"kind": "Secret", "metadata": {"name": "db-password-44444444", "namespace": "shop",
+	      "labels": {"kubepose.project": "shop", "kubepose.secret": "db-password"}}},
+	    {"kind": "ConfigMap", "metadata": {"name": "other-config-66666666", "namespace": "shop",
+	      "labels": {"kubepose.project": "blog", "kubepose.config": "other-config"}}},
+	    {"kind": "ConfigMap", "metadata": {"name": "kube-root-ca.crt", "namespace": "shop"}},
+	    {"kind": "Secret", "metadata": {"name": "tls", "namespace": "shop",
+	      "labels": {"kubepose.project": "shop"}}}
+	  ]
+	}`
+
+	stale, err := kubepose.FindStaleObjects(resources, "shop", "", strings.NewReader(live))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	var got []string
+	for _, object := range stale {
+		got = append(got, object.Kind+"/"+object.Name)
+	}
+	want := []string{"ConfigMap/app-config-11111111", "ConfigMap/web-33333333", "Secret/db-password-55555555"}
+	if strings.Join(got, ",") != strings.Join(want, ",") {
+		t.Fatalf("expected %v, got %v", want, got)
+	}
+
+	t.Run("single object", func(t *testing.T) {
+		t.Parallel()
+		live := `{"kind": "Secret", "metadata": {"name": "db-password-55555555",
+		  "labels": {"kubepose.project": "shop", "kubepose.secret": "db-password"}}}`
+		stale, err := kubepose.FindStaleObjects(resources, "shop", "", strings.NewReader(live))
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if len(stale) != 1 || stale[0].Name != "db-password-55555555" {
+			t.Fatalf("expected the secret to be stale, got %v", stale)
+		}
+	})
+
+	t.Run("objects of other namespaces are kept", func(t *testing.T) {
+		t.Parallel()
+		live := `{"kind": "List", "items": [
+		  {"kind": "Secret", "metadata": {"name": "db-password-55555555", "namespace": "staging",
+		    "labels": {"kubepose.project": "shop", "kubepose.secret": "db-password"}}},
+		  {"kind": "Secret", "metadata": {"name": "db-password-66666666", "namespace": "prod",
+		    "labels": {"kubepose.project": "shop", "kubepose.secret": "db-password"}}}
+		]}`
+		stale, err := kubepose.FindStaleObjects(resources, "shop", "prod", strings.NewReader(live))
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if len(stale) != 1 || stale[0].Namespace != "prod" || stale[0].Name != "db-password-66666666" {
+			t.Fatalf("expected only the prod secret to be stale, got %v", stale)
+		}
+
+		_, err = kubepose.FindStaleObjects(resources, "shop", "", strings.NewReader(live))
+		if err == nil || !strings.Contains(err.Error(), "span the namespaces prod, staging") {
+			t.Fatalf("expected a namespace error, got: %v", err)
+		}
+	})
+
+	t.Run("namespace from x-kubepose.namespace", func(t *testing.T) {
+		t.Parallel()
+		resources := &kubepose.Resources{
+			Secrets: []*corev1.Secret{{ObjectMeta: metav1.ObjectMeta{Name: "db-password-44444444", Namespace: "prod"}}},
+		}
+		live := `{"kind": "List", "items": [
+		  {"kind": "Secret", "metadata": {"name": "db-password-55555555", "namespace": "staging",
+		    "labels": {"kubepose.project": "shop", "kubepose.secret": "db-password"}}}
+		]}`
+		stale, err := kubepose.FindStaleObjects(resources, "shop", "", strings.NewReader(live))
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if len(stale) != 0 {
+			t.Fatalf("expected objects outside prod to be kept, got %v", stale)
+		}
+		_, err = kubepose.FindStaleObjects(resources, "shop", "staging", strings.NewReader(live))
+		if err == nil || !strings.Contains(err.Error(), `differs from the namespace "prod"`) {
+			t.Fatalf("expected a namespace mismatch error, got: %v", err)
+		}
+	})
+
+	t.Run("sealed secrets of current secrets are kept", func(t *testing.T) {
+		t.Parallel()
+		// prune converts without sealing, so resources only has the Secret.
+		live := `{"kind": "List", "items": [
+		  {"kind": "SealedSecret", "metadata": {"name": "db-password-44444444", "namespace": "shop",
+		    "labels": {"kubepose.project": "shop", "kubepose.secret": "db-password"}}},
+		  {"kind": "Secret", "metadata": {"name": "db-password-44444444", "namespace": "shop",
+		    "labels": {"kubepose.project": "shop", "kubepose.secret": "db-password"}}},
+		  {"kind": "SealedSecret", "metadata": {"name": "db-password-55555555", "namespace": "shop",
+		    "labels": {"kubepose.project": "shop", "kubepose.secret": "db-password"}}}
+		]}`
+		stale, err := kubepose.FindStaleObjects(resources, "shop", "", strings.NewReader(live))
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if len(stale) != 1 || stale[0].Kind != "SealedSecret" || stale[0].Name != "db-password-55555555" {
+			t.Fatalf("expected only the superseded SealedSecret to be stale, got %v", stale)
+		}
+	})
+
+	t.Run("external secret targets are kept", func(t *testing.T) {
+		t.Parallel()
+		project := projectWith(types.ServiceConfig{
+			Name: "app", Image: "app", Restart: "always",
+			Secrets: []types.ServiceSecretConfig{{Source: "api-token"}},
+		})
+		project.Name = "shop"
+		project.Secrets = types.Secrets{"api-token": types.SecretConfig{
+			Name: "api-token",
+			Extensions: types.Extensions{"x-kubepose": map[string]any{"externalSecret": map[string]any{
+				"secretStoreRef": map[string]any{"name": "vault"},
+				"remoteRef":      map[string]any{"key": "shop/api-token"},
+			}}},
+		}}
+		resources, err := kubepose.Transformer{}.Convert(project)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if labels := resources.ExternalSecrets[0].Labels; labels[kubepose.SecretLabelKey] != "" {
+			t.Fatalf("expected no %s label on the ExternalSecret, got %v", kubepose.SecretLabelKey, labels)
+		}
+
+		// A Secret labelled by the operator from an older ExternalSecret.
+		live := `{"kind": "Secret", "metadata": {"name": "api-token", "namespace": "shop",
+		  "labels": {"kubepose.project": "shop", "kubepose.secret": "api-token"}}}`
+		stale, err := kubepose.FindStaleObjects(resources, "shop", "", strings.NewReader(live))
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if len(stale) != 0 {
+			t.Fatalf("expected the external secret's Secret to be kept, got %v", stale)
+		}
+	})
+
+	t.Run("invalid JSON returns error", func(t *testing.T) {
+		t.Parallel()
+		_, err := kubepose.FindStaleObjects(resources, "shop", "", strings.NewReader("kind: List"))
+		if err == nil || !strings.Contains(err.Error(), "reading kubectl JSON") {
+			t.Fatalf("expected JSON error, got: %v", err)
+		}
+	})
+}
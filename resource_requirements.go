@@ -0,0 +1,164 @@
+package kubepose
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/compose-spec/compose-go/v2/types"
+	"github.com/docker/go-units"
+	"github.com/sirupsen/logrus"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+)
+
+// gpuResourceNames maps compose device driver names to the extended resource
+// their Kubernetes device plugin advertises. An empty driver is compose's
+// default and, as with docker, means NVIDIA.
+var gpuResourceNames = map[string]corev1.ResourceName{
+	"":       "nvidia.com/gpu",
+	"nvidia": "nvidia.com/gpu",
+	"amd":    "amd.com/gpu",
+}
+
+// validateResources rejects resource settings that have no container-level
+// equivalent in Kubernetes. Called from validateService.
+func validateResources(service types.ServiceConfig) error {
+	if service.Deploy != nil {
+		if limits := service.Deploy.Resources.Limits; limits != nil && limits.Pids > 0 {
+			return fmt.Errorf("deploy.resources.limits.pids: Kubernetes has no per-container PID limit (set podPidsLimit in the kubelet configuration instead)")
+		}
+		if reservations := service.Deploy.Resources.Reservations; reservations != nil {
+			for i, device := range reservations.Devices {
+				if _, err := getGpuResource(service, device); err != nil {
+					return fmt.Errorf("deploy.resources.reservations.devices[%d]: %w", i, err)
+				}
+			}
+		}
+	}
+	for key := range service.StorageOpt {
+		if key != "size" {
+			logrus.Warnf("service %q: storage_opt %s is not supported and will be ignored", service.Name, key)
+		}
+	}
+	if _, err := getEphemeralStorage(service); err != nil {
+		return err
+	}
+	if value, ok := service.Annotations[GpuResourceNameAnnotationKey]; ok && !hasDeviceReservations(service) {
+		return fmt.Errorf("%s %q has no effect without deploy.resources.reservations.devices", GpuResourceNameAnnotationKey, value)
+	}
+	return nil
+}
+
+func hasDeviceReservations(service types.ServiceConfig) bool {
+	return service.Deploy != nil && service.Deploy.Resources.Reservations != nil && len(service.Deploy.Resources.Reservations.Devices) > 0
+}
+
+// getResourceRequirements converts compose deploy.resources and storage_opt
+// into container resource requirements. Unset (zero) values are omitted
+// rather than emitted as a 0 limit, which Kubernetes would enforce.
+func getResourceRequirements(service types.ServiceConfig) corev1.ResourceRequirements {
+	resources := corev1.ResourceRequirements{}
+
+	if service.Deploy != nil {
+		if limits := service.Deploy.Resources.Limits; limits != nil {
+			resources.Limits = cpuMemoryResources(limits)
+		}
+		if reservations := service.Deploy.Resources.Reservations; reservations != nil {
+			resources.Requests = cpuMemoryResources(reservations)
+			// Extended resources cannot be overcommitted: Kubernetes
+			// defaults the request to the limit, and only the limit is
+			// required.
+			for _, device := range reservations.Devices {
+				// Validated in validateService; errors cannot occur here.
+				gpu, _ := getGpuResource(service, device)
+				if resources.Limits == nil {
+					resources.Limits = corev1.ResourceList{}
+				}
+				quantity := resources.Limits[gpu.name]
+				quantity.Add(*resource.NewQuantity(gpu.count, resource.DecimalSI))
+				resources.Limits[gpu.name] = quantity
+			}
+		}
+	}
+
+	// Validated in validateService; parse errors cannot occur here.
+	if storage, _ := getEphemeralStorage(service); storage != nil {
+		if resources.Limits == nil {
+			resources.Limits = corev1.ResourceList{}
+		}
+		resources.Limits[corev1.ResourceEphemeralStorage] = *storage
+	}
+
+	return resources
+}
+
+func cpuMemoryResources(r *types.Resource) corev1.ResourceList {
+	var list corev1.ResourceList
+	set := func(name corev1.ResourceName, quantity resource.Quantity) {
+		if quantity.IsZero() {
+			return
+		}
+		if list == nil {
+			list = corev1.ResourceList{}
+		}
+		list[name] = quantity
+	}
+	set(corev1.ResourceCPU, resource.MustParse(fmt.Sprintf("%dm", int(r.NanoCPUs.Value()*1000))))
+	set(corev1.ResourceMemory, resource.MustParse(fmt.Sprintf("%dMi", r.MemoryBytes/1024/1024)))
+	return list
+}
+
+// getEphemeralStorage returns the container's ephemeral-storage limit from
+// storage_opt size, the writable layer quota docker enforces. Other
+// storage_opt keys are storage driver tuning with no Kubernetes equivalent.
+func getEphemeralStorage(service types.ServiceConfig) (*resource.Quantity, error) {
+	var storage *resource.Quantity
+	for key, value := range service.StorageOpt {
+		if key != "size" {
+			continue
+		}
+		size, err := units.RAMInBytes(value)
+		if err != nil || size <= 0 {
+			return nil, fmt.Errorf("storage_opt size %q: expected a positive size such as 10G", value)
+		}
+		storage = resource.NewQuantity(size, resource.BinarySI)
+	}
+	return storage, nil
+}
+
+type gpuResource struct {
+	name  corev1.ResourceName
+	count int64
+}
+
+// getGpuResource maps a compose GPU device reservation to an extended
+// resource request. Device plugins hand out interchangeable devices, so only
+// an explicit count can be expressed: "all" and device_ids have no
+// equivalent.
+func getGpuResource(service types.ServiceConfig, device types.DeviceRequest) (gpuResource, error) {
+	if !slices.Contains(device.Capabilities, "gpu") {
+		return gpuResource{}, fmt.Errorf("capabilities %v: only gpu devices are supported", device.Capabilities)
+	}
+	if len(device.IDs) > 0 {
+		return gpuResource{}, fmt.Errorf("device_ids cannot be mapped to Kubernetes; use count")
+	}
+	switch {
+	case device.Count == -1:
+		return gpuResource{}, fmt.Errorf("count all cannot be mapped to Kubernetes; use an explicit count")
+	case device.Count <= 0:
+		return gpuResource{}, fmt.Errorf("count is required (compose defaults to all devices, which Kubernetes cannot express)")
+	}
+
+	name, ok := gpuResourceNames[strings.ToLower(device.Driver)]
+	if value, set := service.Annotations[GpuResourceNameAnnotationKey]; set {
+		if !strings.Contains(value, "/") || strings.HasPrefix(value, "kubernetes.io/") {
+			return gpuResource{}, fmt.Errorf("%s %q: expected a domain-prefixed extended resource name such as nvidia.com/gpu", GpuResourceNameAnnotationKey, value)
+		}
+		name, ok = corev1.ResourceName(value), true
+	}
+	if !ok {
+		return gpuResource{}, fmt.Errorf("driver %q has no known Kubernetes resource name; set %s", device.Driver, GpuResourceNameAnnotationKey)
+	}
+	return gpuResource{name: name, count: int64(device.Count)}, nil
+}
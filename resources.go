@@ -1,11 +1,17 @@
 package kubepose
 
 import (
+	"encoding/json"
 	"fmt"
 	"io"
 	"sort"
 	"strings"
 
+	"github.com/middle-management/kubepose/internal/externalsecrets"
+	"github.com/middle-management/kubepose/internal/keda"
+	"github.com/middle-management/kubepose/internal/sealedsecrets"
+	"github.com/middle-management/kubepose/internal/sops"
+	"github.com/middle-management/kubepose/internal/vpa"
 	appsv1 "k8s.io/api/apps/v1"
 	autoscalingv2 "k8s.io/api/autoscaling/v2"
 	batchv1 "k8s.io/api/batch/v1"
@@ -13,6 +19,8 @@ import (
 	networkingv1 "k8s.io/api/networking/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
+	gatewayv1 "sigs.k8s.io/gateway-api/apis/v1"
+	gatewayv1alpha2 "sigs.k8s.io/gateway-api/apis/v1alpha2"
 	"sigs.k8s.io/yaml"
 )
 
@@ -25,9 +33,22 @@ type Resources struct {
 	Deployments              []*appsv1.Deployment
 	CronJobs                 []*batchv1.CronJob
 	HorizontalPodAutoscalers []*autoscalingv2.HorizontalPodAutoscaler
+	ScaledObjects            []*keda.ScaledObject
+	ScaledJobs               []*keda.ScaledJob
+	VerticalPodAutoscalers   []*vpa.VerticalPodAutoscaler
+	ExternalSecrets          []*externalsecrets.ExternalSecret
+	SealedSecrets            []*sealedsecrets.SealedSecret
+	EncryptedSecrets         []*sops.Secret
 	Ingresses                []*networkingv1.Ingress
+	HTTPRoutes               []*gatewayv1.HTTPRoute
+	GRPCRoutes               []*gatewayv1.GRPCRoute
+	TCPRoutes                []*gatewayv1alpha2.TCPRoute
 	PersistentVolumeClaims   []*corev1.PersistentVolumeClaim
 	ServiceAccounts          []*corev1.ServiceAccount
+
+	// Format is the output format of Write: OutputYAML (the default when
+	// empty) or OutputJSON.
+	Format string
 }
 
 type k8sObject interface {
@@ -43,20 +64,36 @@ func toObjects[T k8sObject](items []T) []k8sObject {
 	return result
 }
 
-func (r *Resources) Write(writer io.Writer) error {
+// objects returns all resources, in no particular order.
+func (r *Resources) objects() []k8sObject {
 	var items []k8sObject
 	items = append(items, toObjects(r.ServiceAccounts)...)
 	items = append(items, toObjects(r.ConfigMaps)...)
 	items = append(items, toObjects(r.Secrets)...)
+	items = append(items, toObjects(r.ExternalSecrets)...)
+	items = append(items, toObjects(r.SealedSecrets)...)
+	items = append(items, toObjects(r.EncryptedSecrets)...)
 	items = append(items, toObjects(r.DaemonSets)...)
 	items = append(items, toObjects(r.Deployments)...)
 	items = append(items, toObjects(r.CronJobs)...)
 	items = append(items, toObjects(r.HorizontalPodAutoscalers)...)
+	items = append(items, toObjects(r.ScaledObjects)...)
+	items = append(items, toObjects(r.ScaledJobs)...)
+	items = append(items, toObjects(r.VerticalPodAutoscalers)...)
 	items = append(items, toObjects(r.Pods)...)
 	items = append(items, toObjects(r.Services)...)
 	items = append(items, toObjects(r.Ingresses)...)
+	items = append(items, toObjects(r.HTTPRoutes)...)
+	items = append(items, toObjects(r.GRPCRoutes)...)
+	items = append(items, toObjects(r.TCPRoutes)...)
 	items = append(items, toObjects(r.PersistentVolumeClaims)...)
+	return items
+}
 
+// Write writes the resources sorted by kind and name, as YAML documents or,
+// with OutputJSON, as a JSON v1 List.
+func (r *Resources) Write(writer io.Writer) error {
+	items := r.objects()
 	sort.Slice(items, func(i, j int) bool {
 		ki := items[i].GetObjectKind().GroupVersionKind().Kind
 		kj := items[j].GetObjectKind().GroupVersionKind().Kind
@@ -68,6 +105,22 @@ func (r *Resources) Write(writer io.Writer) error {
 		return ni < nj
 	})
 
+	switch r.Format {
+	case "", OutputYAML:
+	case OutputJSON:
+		list := map[string]any{"apiVersion": "v1", "kind": "List", "items": items}
+		data, err := json.MarshalIndent(list, "", "  ")
+		if err != nil {
+			return fmt.Errorf("error marshaling resources: %w", err)
+		}
+		if _, err := writer.Write(append(data, '\n')); err != nil {
+			return fmt.Errorf("error writing resources: %w", err)
+		}
+		return nil
+	default:
+		return fmt.Errorf("unsupported output format %q (expected %q or %q)", r.Format, OutputYAML, OutputJSON)
+	}
+
 	var allResources []string
 	for _, item := range items {
 		yamlData, err := yaml.Marshal(item)
@@ -0,0 +1,51 @@
+package kubepose
+
+import (
+	"crypto/rand"
+	"fmt"
+	"time"
+
+	"github.com/middle-management/kubepose/internal/sealedsecrets"
+	"github.com/middle-management/kubepose/internal/sops"
+)
+
+// encryptSecrets replaces the plaintext Secrets generated from compose
+// secrets with SealedSecrets or SOPS-encrypted Secrets, so the output can be
+// committed. Names keep their content hash, so a changed secret still rolls
+// the pods mounting it.
+func (t Transformer) encryptSecrets(resources *Resources) error {
+	switch {
+	case t.SealPublicKey != nil:
+		for _, secret := range resources.Secrets {
+			// Secrets placed in a namespace by x-kubepose.namespace are
+			// sealed in strict scope for it.
+			namespace := t.SealNamespace
+			if namespace == "" {
+				namespace = secret.Namespace
+			}
+			if t.SealClusterWide {
+				namespace = ""
+			} else if namespace == "" {
+				return fmt.Errorf("sealing secret %s: no namespace to seal it for; set a seal namespace or %s.namespace, or seal cluster-wide", secret.Name, ServiceExtensionKey)
+			}
+			sealed, err := sealedsecrets.Seal(rand.Reader, t.SealPublicKey, secret, namespace)
+			if err != nil {
+				return err
+			}
+			resources.SealedSecrets = append(resources.SealedSecrets, sealed)
+		}
+	case len(t.SopsAgeRecipients) > 0:
+		now := time.Now()
+		for _, secret := range resources.Secrets {
+			encrypted, err := sops.Encrypt(secret, t.SopsAgeRecipients, now)
+			if err != nil {
+				return fmt.Errorf("encrypting secret %s: %w", secret.Name, err)
+			}
+			resources.EncryptedSecrets = append(resources.EncryptedSecrets, encrypted)
+		}
+	default:
+		return nil
+	}
+	resources.Secrets = nil
+	return nil
+}
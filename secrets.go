@@ -9,7 +9,9 @@ import (
 	"os"
 	"path/filepath"
 
+	"filippo.io/age"
 	"github.com/compose-spec/compose-go/v2/types"
+	"github.com/middle-management/kubepose/internal/sops"
 	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/utils/ptr"
@@ -26,8 +28,28 @@ type SecretMapping struct {
 
 func (t Transformer) processSecrets(project *types.Project, resources *Resources) (map[string]SecretMapping, error) {
 	secretMapping := make(map[string]SecretMapping)
+	var identities []age.Identity
 
 	for name, secret := range project.Secrets {
+		es, err := getExternalSecretExtension(secret)
+		if err != nil {
+			return nil, fmt.Errorf("secret %s: %w", name, err)
+		}
+		if es != nil {
+			// The operator provides the value in the cluster; a file or
+			// environment source only serves local compose runs.
+			targetName := name
+			if secret.External {
+				targetName = secret.Name
+			}
+			t.createExternalSecret(resources, project, name, targetName, secret, es)
+			secretMapping[name] = SecretMapping{
+				Name:    targetName,
+				SubPath: name,
+			}
+			continue
+		}
+
 		var content []byte
 		var shortHash string
 		if secret.Environment != "" {
@@ -45,6 +67,23 @@ func (t Transformer) processSecrets(project *types.Project, resources *Resources
 			}
 			content = fileContent
 			shortHash = fileHash
+
+			if sopsEncrypted, err := isSopsSecret(name, secret, content); err != nil {
+				return nil, err
+			} else if sopsEncrypted {
+				if identities == nil {
+					if identities, err = sops.LoadIdentities(); err != nil {
+						return nil, fmt.Errorf("secret %s (file %s) is SOPS-encrypted: %w", name, secret.File, err)
+					}
+				}
+				content, err = sops.Decrypt(content, sops.FormatForPath(secret.File), identities)
+				if err != nil {
+					return nil, fmt.Errorf("secret %s (file %s): %w", name, secret.File, err)
+				}
+				// Hash the plaintext: re-encrypting an unchanged secret
+				// must not roll the pods mounting it.
+				_, shortHash = getContentHash(content, secretHmacKey)
+			}
 		} else if secret.External {
 			secretMapping[name] = SecretMapping{
 				Name:     secret.Name,
@@ -54,6 +93,13 @@ func (t Transformer) processSecrets(project *types.Project, resources *Resources
 			continue
 		}
 
+		if err := checkObjectSize(content); err != nil {
+			if secret.File != "" {
+				return nil, fmt.Errorf("secret %s (file %s): %w", name, secret.File, err)
+			}
+			return nil, fmt.Errorf("secret %s: %w", name, err)
+		}
+
 		k8sSecretName := fmt.Sprintf("%s-%s", name, shortHash)
 		secretMapping[name] = SecretMapping{
 			Name:    k8sSecretName,
@@ -66,8 +112,11 @@ func (t Transformer) processSecrets(project *types.Project, resources *Resources
 				Kind:       "Secret",
 			},
 			ObjectMeta: metav1.ObjectMeta{
-				Name:   k8sSecretName,
-				Labels: mergeMaps(secret.Labels, t.Labels),
+				Name: k8sSecretName,
+				Labels: mergeMaps(secret.Labels, t.Labels, map[string]string{
+					ProjectLabelKey: project.Name,
+					SecretLabelKey:  name,
+				}),
 				Annotations: mergeMaps(t.Annotations, map[string]string{
 					SecretHmacKeyAnnotationKey: secretHmacKey,
 				}),
@@ -86,6 +135,24 @@ func (t Transformer) processSecrets(project *types.Project, resources *Resources
 	return secretMapping, nil
 }
 
+// isSopsSecret reports whether a secret file must be decrypted with SOPS,
+// either because its kubepose.secret.decrypt label says so or because it
+// carries SOPS metadata.
+func isSopsSecret(name string, secret types.SecretConfig, content []byte) (bool, error) {
+	encrypted := sops.IsEncrypted(content, sops.FormatForPath(secret.File))
+	switch mode := secret.Labels[SecretDecryptLabelKey]; mode {
+	case "":
+		return encrypted, nil
+	case "sops":
+		if !encrypted {
+			return false, fmt.Errorf("secret %s: %s is sops but file %s has no SOPS metadata", name, SecretDecryptLabelKey, secret.File)
+		}
+		return true, nil
+	default:
+		return false, fmt.Errorf("secret %s: unsupported %s %q (expected \"sops\")", name, SecretDecryptLabelKey, mode)
+	}
+}
+
 // readFileWithShortHash reads the content of a secret file and its hash
 func readFileWithShortHash(path string, hmacKey string) ([]byte, string, error) {
 	file, err := os.Open(path)
@@ -111,12 +178,15 @@ func (t Transformer) updatePodSpecWithSecrets(spec *corev1.PodSpec, service type
 	for _, serviceSecret := range service.Secrets {
 		if mapping, exists := secretMappings[serviceSecret.Source]; exists {
 			var optional *bool
-			if mapping.External {
+			if mapping.External && !t.RequireExternalSecrets {
 				optional = ptr.To(true)
 			}
 
+			ref := types.FileReferenceConfig(serviceSecret)
+			warnFileOwnership(spec, service, "secret", ref)
+
 			// Add volume if it doesn't already exist
-			volumeName := serviceSecret.Source
+			volumeName := fileReferenceVolumeName(ref)
 			volumeExists := false
 			for _, v := range spec.Volumes {
 				if v.Name == volumeName {
@@ -130,8 +200,9 @@ func (t Transformer) updatePodSpecWithSecrets(spec *corev1.PodSpec, service type
 					Name: volumeName,
 					VolumeSource: corev1.VolumeSource{
 						Secret: &corev1.SecretVolumeSource{
-							SecretName: mapping.Name,
-							Optional:   optional,
+							SecretName:  mapping.Name,
+							Optional:    optional,
+							DefaultMode: fileReferenceMode(ref),
 						},
 					},
 				}
@@ -1,6 +1,8 @@
 package kubepose
 
 import (
+	"fmt"
+	"net"
 	"strconv"
 	"strings"
 
@@ -9,9 +11,16 @@ import (
 	networkingv1 "k8s.io/api/networking/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/util/intstr"
+	"k8s.io/apimachinery/pkg/util/validation"
+)
+
+// Default Kubernetes NodePort range. Published ports inside it are pinned as
+// node ports on NodePort and LoadBalancer Services.
+const (
+	serviceNodePortMin = 30000
+	serviceNodePortMax = 32767
 )
 
-// TODO support LoadBalancer, NodePort, ExternalName, ClusterIP types via annotations.
 func (t Transformer) createService(service types.ServiceConfig) *corev1.Service {
 	serviceName := getServiceName(service)
 	ports := appendExposePorts(convertServicePorts(service.Ports), service.Expose)
@@ -21,10 +30,31 @@ func (t Transformer) createService(service types.ServiceConfig) *corev1.Service
 		},
 		Ports: ports,
 	}
-	if len(ports) == 0 {
-		// Compose gives every service a DNS name whether or not it publishes
-		// ports. A headless Service keeps that contract on Kubernetes.
-		spec.ClusterIP = corev1.ClusterIPNone
+	switch serviceType := getServiceType(service); serviceType {
+	case corev1.ServiceTypeExternalName:
+		// Values are validated in validateService; decode errors cannot occur here.
+		ext, _ := getServiceExtension(service)
+		spec = corev1.ServiceSpec{
+			Type:         corev1.ServiceTypeExternalName,
+			ExternalName: ext.ExternalName,
+			Ports:        ports,
+		}
+	case corev1.ServiceTypeNodePort, corev1.ServiceTypeLoadBalancer:
+		spec.Type = serviceType
+		spec.ExternalTrafficPolicy = corev1.ServiceExternalTrafficPolicy(service.Annotations[ServiceExternalTrafficPolicyAnnotationKey])
+		if serviceType == corev1.ServiceTypeLoadBalancer {
+			spec.LoadBalancerSourceRanges = splitList(service.Annotations[ServiceLoadBalancerSourceRangesAnnotationKey])
+			if class, ok := service.Annotations[ServiceLoadBalancerClassAnnotationKey]; ok {
+				spec.LoadBalancerClass = &class
+			}
+		}
+		assignNodePorts(&spec)
+	default:
+		if len(ports) == 0 {
+			// Compose gives every service a DNS name whether or not it publishes
+			// ports. A headless Service keeps that contract on Kubernetes.
+			spec.ClusterIP = corev1.ClusterIPNone
+		}
 	}
 	return &corev1.Service{
 		TypeMeta: metav1.TypeMeta{
@@ -40,6 +70,191 @@ func (t Transformer) createService(service types.ServiceConfig) *corev1.Service
 	}
 }
 
+// getServiceType returns the Service type selected by kubepose.service.type,
+// matched case-insensitively. A service with x-kubepose.externalName defaults
+// to ExternalName; everything else defaults to "" (ClusterIP).
+func getServiceType(service types.ServiceConfig) corev1.ServiceType {
+	value, ok := service.Annotations[ServiceTypeAnnotationKey]
+	if !ok {
+		if ext, _ := getServiceExtension(service); ext.ExternalName != "" {
+			return corev1.ServiceTypeExternalName
+		}
+		return ""
+	}
+	for _, serviceType := range []corev1.ServiceType{
+		corev1.ServiceTypeClusterIP,
+		corev1.ServiceTypeNodePort,
+		corev1.ServiceTypeLoadBalancer,
+		corev1.ServiceTypeExternalName,
+	} {
+		if strings.EqualFold(value, string(serviceType)) {
+			return serviceType
+		}
+	}
+	return corev1.ServiceType(value)
+}
+
+// assignNodePorts pins each port's nodePort to its Service port when that
+// port falls in the NodePort range, so a compose "30080:80" is reachable on
+// 30080 on every node. Other ports are left for Kubernetes to allocate.
+func assignNodePorts(spec *corev1.ServiceSpec) {
+	if spec.Type != corev1.ServiceTypeNodePort && spec.Type != corev1.ServiceTypeLoadBalancer {
+		return
+	}
+	for i := range spec.Ports {
+		port := &spec.Ports[i]
+		if port.NodePort == 0 && port.Port >= serviceNodePortMin && port.Port <= serviceNodePortMax {
+			port.NodePort = port.Port
+		}
+	}
+}
+
+// validateServiceType rejects kubepose.service.type settings Kubernetes would
+// refuse or silently ignore. Called from validateService.
+func validateServiceType(service types.ServiceConfig) error {
+	ext, err := getServiceExtension(service)
+	if err != nil {
+		return err
+	}
+
+	serviceType := getServiceType(service)
+	switch serviceType {
+	case "", corev1.ServiceTypeClusterIP, corev1.ServiceTypeNodePort, corev1.ServiceTypeLoadBalancer, corev1.ServiceTypeExternalName:
+	default:
+		return fmt.Errorf("unsupported %s %q (expected ClusterIP, NodePort, LoadBalancer or ExternalName)", ServiceTypeAnnotationKey, serviceType)
+	}
+
+	isLoadBalancer := serviceType == corev1.ServiceTypeLoadBalancer
+	isNodePort := serviceType == corev1.ServiceTypeNodePort || isLoadBalancer
+	if ranges, ok := service.Annotations[ServiceLoadBalancerSourceRangesAnnotationKey]; ok {
+		if !isLoadBalancer {
+			return fmt.Errorf("%s requires %s: LoadBalancer", ServiceLoadBalancerSourceRangesAnnotationKey, ServiceTypeAnnotationKey)
+		}
+		for _, cidr := range splitList(ranges) {
+			if _, _, err := net.ParseCIDR(cidr); err != nil {
+				return fmt.Errorf("%s: invalid CIDR %q", ServiceLoadBalancerSourceRangesAnnotationKey, cidr)
+			}
+		}
+	}
+	if _, ok := service.Annotations[ServiceLoadBalancerClassAnnotationKey]; ok && !isLoadBalancer {
+		return fmt.Errorf("%s requires %s: LoadBalancer", ServiceLoadBalancerClassAnnotationKey, ServiceTypeAnnotationKey)
+	}
+	if policy, ok := service.Annotations[ServiceExternalTrafficPolicyAnnotationKey]; ok {
+		if !isNodePort {
+			return fmt.Errorf("%s requires %s: NodePort or LoadBalancer", ServiceExternalTrafficPolicyAnnotationKey, ServiceTypeAnnotationKey)
+		}
+		switch corev1.ServiceExternalTrafficPolicy(policy) {
+		case corev1.ServiceExternalTrafficPolicyCluster, corev1.ServiceExternalTrafficPolicyLocal:
+		default:
+			return fmt.Errorf("%s must be Cluster or Local, got %q", ServiceExternalTrafficPolicyAnnotationKey, policy)
+		}
+	}
+	if isNodePort && len(service.Ports) == 0 && len(service.Expose) == 0 {
+		return fmt.Errorf("%s: %s requires ports", ServiceTypeAnnotationKey, serviceType)
+	}
+
+	if serviceType == corev1.ServiceTypeExternalName {
+		if ext.ExternalName == "" {
+			return fmt.Errorf("%s: ExternalName requires %s.externalName", ServiceTypeAnnotationKey, ServiceExtensionKey)
+		}
+		if errs := validation.IsDNS1123Subdomain(ext.ExternalName); len(errs) > 0 {
+			return fmt.Errorf("%s.externalName %q: %s", ServiceExtensionKey, ext.ExternalName, strings.Join(errs, ", "))
+		}
+		// An ExternalName service emits no workload, so settings that shape
+		// one would be silently dropped.
+		for _, key := range []string{ServiceGroupAnnotationKey, CronJobScheduleAnnotationKey, HpaMaxReplicasAnnotationKey, VpaUpdateModeAnnotationKey, ContainerTypeAnnotationKey} {
+			if _, ok := service.Annotations[key]; ok {
+				return fmt.Errorf("%s cannot be combined with an ExternalName service", key)
+			}
+		}
+		if hasKeda(service) {
+			return fmt.Errorf("%s.keda cannot be combined with an ExternalName service", ServiceExtensionKey)
+		}
+	} else if ext.ExternalName != "" {
+		return fmt.Errorf("%s.externalName requires %s: ExternalName, got %q", ServiceExtensionKey, ServiceTypeAnnotationKey, serviceType)
+	}
+
+	for _, link := range service.ExternalLinks {
+		if _, _, err := parseExternalLink(link); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// parseExternalLink splits a compose external_links entry ("target" or
+// "target:alias") into the Service name to create and the DNS name it
+// points at.
+func parseExternalLink(link string) (name, externalName string, err error) {
+	externalName, name, _ = strings.Cut(link, ":")
+	if name == "" {
+		name = externalName
+	}
+	if errs := validation.IsDNS1035Label(name); len(errs) > 0 {
+		return "", "", fmt.Errorf("external_links %q: %q is not a valid Service name (use \"target:alias\"): %s", link, name, strings.Join(errs, ", "))
+	}
+	if errs := validation.IsDNS1123Subdomain(externalName); len(errs) > 0 {
+		return "", "", fmt.Errorf("external_links %q: %q is not a valid DNS name: %s", link, externalName, strings.Join(errs, ", "))
+	}
+	return name, externalName, nil
+}
+
+// createExternalLinkServices emits an ExternalName Service for every compose
+// external_links entry, so the alias resolves inside the cluster the way it
+// does on the compose network. Entries are validated in validateService.
+func (t Transformer) createExternalLinkServices(project *types.Project, resources *Resources) error {
+	for _, serviceName := range project.ServiceNames() {
+		for _, link := range project.Services[serviceName].ExternalLinks {
+			name, externalName, _ := parseExternalLink(link)
+
+			var existing *corev1.Service
+			for _, s := range resources.Services {
+				if s.ObjectMeta.Name == name {
+					existing = s
+					break
+				}
+			}
+			if existing != nil {
+				if existing.Spec.Type == corev1.ServiceTypeExternalName && existing.Spec.ExternalName == externalName {
+					continue
+				}
+				return fmt.Errorf("service %q: external_links %q conflicts with existing Service %q", serviceName, link, name)
+			}
+
+			resources.Services = append(resources.Services, &corev1.Service{
+				TypeMeta: metav1.TypeMeta{
+					APIVersion: "v1",
+					Kind:       "Service",
+				},
+				ObjectMeta: metav1.ObjectMeta{
+					Name:        name,
+					Annotations: mergeMaps(t.Annotations),
+					Labels:      mergeMaps(t.Labels),
+				},
+				Spec: corev1.ServiceSpec{
+					Type:         corev1.ServiceTypeExternalName,
+					ExternalName: externalName,
+				},
+			})
+		}
+	}
+	return nil
+}
+
+// splitList splits a comma or newline separated annotation value, dropping
+// empty entries.
+func splitList(value string) []string {
+	var items []string
+	for _, line := range strings.Split(value, "\n") {
+		for _, part := range strings.Split(line, ",") {
+			if item := strings.TrimSpace(part); item != "" {
+				items = append(items, item)
+			}
+		}
+	}
+	return items
+}
+
 // appendExposePorts adds compose expose entries ("port" or "port/protocol")
 // as Service ports, skipping targets already covered by published ports.
 // Entries are validated in validateService, so parse failures are skipped.
@@ -65,16 +280,28 @@ func appendExposePorts(ports []corev1.ServicePort, expose []string) []corev1.Ser
 	return ports
 }
 
-// mergeServicePorts folds another group member's ports into an existing
-// Service, so port declarations survive regardless of which member was
-// converted first. A headless placeholder becomes a normal ClusterIP Service
-// once any member contributes ports.
-func mergeServicePorts(existing *corev1.Service, ports []corev1.ServicePort) {
+// mergeServicePorts folds another group member's Service into an existing
+// one, so port declarations survive regardless of which member was converted
+// first. A headless placeholder becomes a normal ClusterIP Service once any
+// member contributes ports. A member selecting NodePort or LoadBalancer
+// promotes the shared Service, and node ports are pinned for every member's
+// ports; two members selecting different types is an error.
+func mergeServicePorts(existing *corev1.Service, incoming *corev1.Service) error {
+	if incoming.Spec.Type != "" && incoming.Spec.Type != existing.Spec.Type {
+		if existing.Spec.Type != "" {
+			return fmt.Errorf("conflicting %s values %q and %q for Service %q", ServiceTypeAnnotationKey, existing.Spec.Type, incoming.Spec.Type, existing.Name)
+		}
+		existing.Spec.Type = incoming.Spec.Type
+		existing.Spec.ExternalTrafficPolicy = incoming.Spec.ExternalTrafficPolicy
+		existing.Spec.LoadBalancerSourceRanges = incoming.Spec.LoadBalancerSourceRanges
+		existing.Spec.LoadBalancerClass = incoming.Spec.LoadBalancerClass
+	}
+
 	seen := make(map[int32]bool)
 	for _, p := range existing.Spec.Ports {
 		seen[p.Port] = true
 	}
-	for _, p := range ports {
+	for _, p := range incoming.Spec.Ports {
 		if seen[p.Port] {
 			continue
 		}
@@ -84,6 +311,8 @@ func mergeServicePorts(existing *corev1.Service, ports []corev1.ServicePort) {
 	if len(existing.Spec.Ports) > 0 {
 		existing.Spec.ClusterIP = ""
 	}
+	assignNodePorts(&existing.Spec)
+	return nil
 }
 
 func convertServicePorts(ports []types.ServicePortConfig) []corev1.ServicePort {
@@ -93,12 +322,19 @@ func convertServicePorts(ports []types.ServicePortConfig) []corev1.ServicePort {
 		if port.Published != "" {
 			published, _ = strconv.Atoi(port.Published)
 		}
+		name := port.Name
+		if name == "" {
+			name = strconv.Itoa(published)
+		}
 		servicePort := corev1.ServicePort{
-			Name:       strconv.Itoa(published),
+			Name:       name,
 			Port:       int32(published),
 			TargetPort: intstr.FromInt(int(port.Target)),
 			Protocol:   convertProtocol(port.Protocol),
 		}
+		if port.AppProtocol != "" {
+			servicePort.AppProtocol = &port.AppProtocol
+		}
 		servicePorts = append(servicePorts, servicePort)
 	}
 	return servicePorts
@@ -124,55 +360,20 @@ func (t Transformer) createIngress(service types.ServiceConfig) *networkingv1.In
 		ingressClassName = &class
 	}
 
-	// Get host from labels or annotations
-	var hosts []string
-	if expose, ok := service.Annotations[ServiceExposeAnnotationKey]; ok && expose != "true" {
-		for _, line := range strings.Split(expose, "\n") {
-			for _, part := range strings.Split(line, ",") {
-				host := strings.TrimSpace(part)
-				if host != "" {
-					hosts = append(hosts, host)
-				}
-			}
-		}
-	} else {
-		// Default host
-		hosts = append(hosts, service.Name)
-	}
+	hosts := getExposeHosts(service)
 	if len(hosts) == 0 {
 		return nil
 	}
 
-	// Find the first HTTP port
-	var servicePort int32
-	for _, port := range service.Ports {
-		if port.Protocol == "" || strings.ToUpper(port.Protocol) == "TCP" {
-			published := int32(port.Target)
-			if port.Published != "" {
-				if p, err := strconv.Atoi(port.Published); err == nil {
-					published = int32(p)
-				}
-			}
-			servicePort = published
-			break
-		}
-	}
-	if servicePort == 0 {
-		for _, e := range service.Expose {
-			target, protocol, _ := strings.Cut(e, "/")
-			if protocol != "" && !strings.EqualFold(protocol, "tcp") {
-				continue
-			}
-			if p, err := strconv.Atoi(target); err == nil {
-				servicePort = int32(p)
-				break
-			}
-		}
-	}
-	if servicePort == 0 {
+	servicePort := getExposePort(service)
+	if servicePort.Number == 0 {
 		// A portless service has nothing an Ingress could route to.
 		return nil
 	}
+	backendPort := networkingv1.ServiceBackendPort{Number: servicePort.Number}
+	if servicePort.Name != "" {
+		backendPort = networkingv1.ServiceBackendPort{Name: servicePort.Name}
+	}
 
 	var rules []networkingv1.IngressRule
 	for _, host := range hosts {
@@ -187,9 +388,7 @@ func (t Transformer) createIngress(service types.ServiceConfig) *networkingv1.In
 							Backend: networkingv1.IngressBackend{
 								Service: &networkingv1.IngressServiceBackend{
 									Name: service.Name,
-									Port: networkingv1.ServiceBackendPort{
-										Number: servicePort,
-									},
+									Port: backendPort,
 								},
 							},
 						},
@@ -216,6 +415,72 @@ func (t Transformer) createIngress(service types.ServiceConfig) *networkingv1.In
 	}
 }
 
+// getExposeHosts returns the hosts listed in kubepose.service.expose, split on
+// newlines and commas, or the service name when the value is "true".
+func getExposeHosts(service types.ServiceConfig) []string {
+	var hosts []string
+	if expose, ok := service.Annotations[ServiceExposeAnnotationKey]; ok && expose != "true" {
+		for _, line := range strings.Split(expose, "\n") {
+			for _, part := range strings.Split(line, ",") {
+				host := strings.TrimSpace(part)
+				if host != "" {
+					hosts = append(hosts, host)
+				}
+			}
+		}
+	} else {
+		// Default host
+		hosts = append(hosts, service.Name)
+	}
+	return hosts
+}
+
+// exposePort is the Service port an exposed service is routed to.
+type exposePort struct {
+	Number int32
+	// Name is set when the compose port is named, so Ingress backends can
+	// reference it by name.
+	Name        string
+	AppProtocol string
+}
+
+// getExposePort returns the Service port an exposed service is routed to: the
+// port named or numbered by kubepose.service.expose.port, else the first TCP
+// port, falling back to the first TCP expose entry. The port's app_protocol
+// is returned alongside so callers can pick a matching route kind. A zero
+// port means there is nothing to route to.
+func getExposePort(service types.ServiceConfig) exposePort {
+	want, hasWant := service.Annotations[ServiceExposePortAnnotationKey]
+	for _, port := range service.Ports {
+		if port.Protocol != "" && strings.ToUpper(port.Protocol) != "TCP" {
+			continue
+		}
+		published := int32(port.Target)
+		if port.Published != "" {
+			if p, err := strconv.Atoi(port.Published); err == nil {
+				published = int32(p)
+			}
+		}
+		if hasWant && want != port.Name && want != strconv.Itoa(int(published)) {
+			continue
+		}
+		return exposePort{Number: published, Name: port.Name, AppProtocol: port.AppProtocol}
+	}
+	for _, e := range service.Expose {
+		target, protocol, _ := strings.Cut(e, "/")
+		if protocol != "" && !strings.EqualFold(protocol, "tcp") {
+			continue
+		}
+		if hasWant && want != target {
+			continue
+		}
+		if p, err := strconv.Atoi(target); err == nil {
+			return exposePort{Number: int32(p)}
+		}
+	}
+	return exposePort{}
+}
+
 func (t Transformer) createServiceAccount(name string, service types.ServiceConfig) *corev1.ServiceAccount {
 	return &corev1.ServiceAccount{
 		TypeMeta: metav1.TypeMeta{
@@ -0,0 +1,110 @@
+package kubepose
+
+import (
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/compose-spec/compose-go/v2/types"
+	"github.com/sirupsen/logrus"
+	corev1 "k8s.io/api/core/v1"
+)
+
+// safeSysctls are the sysctls every kubelet allows. Other namespaced sysctls
+// must be enabled per node with --allowed-unsafe-sysctls.
+// https://kubernetes.io/docs/tasks/administer-cluster/sysctl-cluster/
+var safeSysctls = []string{
+	"kernel.shm_rmid_forced",
+	"net.ipv4.ip_local_port_range",
+	"net.ipv4.ip_local_reserved_ports",
+	"net.ipv4.ip_unprivileged_port_start",
+	"net.ipv4.ping_group_range",
+	"net.ipv4.tcp_fin_timeout",
+	"net.ipv4.tcp_keepalive_intvl",
+	"net.ipv4.tcp_keepalive_probes",
+	"net.ipv4.tcp_keepalive_time",
+	"net.ipv4.tcp_rmem",
+	"net.ipv4.tcp_syncookies",
+	"net.ipv4.tcp_wmem",
+}
+
+// ipcSysctl reports whether a sysctl belongs to the IPC namespace, mirroring
+// the kubelet's namespaced sysctl groups.
+func ipcSysctl(name string) bool {
+	return strings.HasPrefix(name, "kernel.shm") ||
+		strings.HasPrefix(name, "kernel.msg") ||
+		name == "kernel.sem" ||
+		strings.HasPrefix(name, "fs.mqueue.")
+}
+
+func networkSysctl(name string) bool {
+	return strings.HasPrefix(name, "net.")
+}
+
+// validateSysctls rejects sysctls a pod cannot set: node-level ones, which
+// would change the kernel for every pod on the node, and namespaced ones
+// whose namespace is shared with the host. ulimits have no Kubernetes field
+// and are reported rather than silently dropped. Called from validateService.
+func validateSysctls(service types.ServiceConfig) error {
+	for _, key := range sortedKeys(service.Sysctls) {
+		// The kubelet accepts both separators; classify on the dotted form.
+		name := strings.ReplaceAll(key, "/", ".")
+		switch {
+		case networkSysctl(name):
+			if service.NetworkMode == "host" {
+				return fmt.Errorf("sysctls %s: network sysctls cannot be set with network_mode: host", key)
+			}
+		case ipcSysctl(name):
+			if service.Ipc == "host" {
+				return fmt.Errorf("sysctls %s: IPC sysctls cannot be set with ipc: host", key)
+			}
+		default:
+			return fmt.Errorf("sysctls %s: only namespaced sysctls (net.*, kernel.shm*, kernel.msg*, kernel.sem, fs.mqueue.*) can be set per pod", key)
+		}
+		if !slices.Contains(safeSysctls, name) {
+			logrus.Warnf("service %q: sysctl %s is unsafe and requires the kubelet's --allowed-unsafe-sysctls on every node it may run on", service.Name, key)
+		}
+	}
+	for _, name := range sortedKeys(service.Ulimits) {
+		logrus.Warnf("service %q: ulimits %s has no Kubernetes equivalent and will be ignored (containers get the node runtime's limits)", service.Name, name)
+	}
+	return nil
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func getSysctls(service types.ServiceConfig) []corev1.Sysctl {
+	var sysctls []corev1.Sysctl
+	for _, name := range sortedKeys(service.Sysctls) {
+		sysctls = append(sysctls, corev1.Sysctl{Name: name, Value: service.Sysctls[name]})
+	}
+	return sysctls
+}
+
+// mergePodSysctls folds a grouped service's sysctls into the shared pod's
+// security context. Sysctls apply to the whole pod, so members may repeat a
+// value but not set a different one.
+func mergePodSysctls(podSpec *corev1.PodSpec, service types.ServiceConfig) error {
+	for _, sysctl := range getSysctls(service) {
+		if podSpec.SecurityContext == nil {
+			podSpec.SecurityContext = &corev1.PodSecurityContext{}
+		}
+		i := slices.IndexFunc(podSpec.SecurityContext.Sysctls, func(s corev1.Sysctl) bool { return s.Name == sysctl.Name })
+		if i < 0 {
+			podSpec.SecurityContext.Sysctls = append(podSpec.SecurityContext.Sysctls, sysctl)
+			continue
+		}
+		if existing := podSpec.SecurityContext.Sysctls[i].Value; existing != sysctl.Value {
+			return fmt.Errorf("service %q: sysctls %s=%q conflicts with %q from another service in the same pod", service.Name, sysctl.Name, sysctl.Value, existing)
+		}
+	}
+	return nil
+}
@@ -107,7 +107,7 @@ func isFilePath(path string) bool {
 	return !info.IsDir()
 }
 
-func (t Transformer) updatePodSpecWithVolumes(spec *corev1.PodSpec, service types.ServiceConfig, volumeMappings map[string]VolumeMapping, resources *Resources) error {
+func (t Transformer) updatePodSpecWithVolumes(spec *corev1.PodSpec, project *types.Project, service types.ServiceConfig, volumeMappings map[string]VolumeMapping, resources *Resources) error {
 	// Track which containers need which volumes
 	containerVolumes := make(map[string][]corev1.VolumeMount)
 
@@ -154,6 +154,9 @@ func (t Transformer) updatePodSpecWithVolumes(spec *corev1.PodSpec, service type
 			if err != nil {
 				return fmt.Errorf("failed to read volume file %s: %w", serviceVolume.Source, err)
 			}
+			if err := checkObjectSize(content); err != nil {
+				return fmt.Errorf("bind mount of file %s: %w; use a hostPath or persistent volume instead", serviceVolume.Source, err)
+			}
 
 			// Create ConfigMap if it doesn't exist
 			configMapName := fmt.Sprintf("%s-%s", service.Name, hash)
@@ -175,16 +178,17 @@ func (t Transformer) updatePodSpecWithVolumes(spec *corev1.PodSpec, service type
 						Kind:       "ConfigMap",
 					},
 					ObjectMeta: metav1.ObjectMeta{
-						Name:   configMapName,
-						Labels: mergeMaps(service.Labels, t.Labels),
+						Name: configMapName,
+						Labels: mergeMaps(service.Labels, t.Labels, map[string]string{
+							ProjectLabelKey:       project.Name,
+							VolumeServiceLabelKey: service.Name,
+						}),
 						Annotations: mergeMaps(t.Annotations, map[string]string{
 							VolumeHmacKeyAnnotationKey: volumeHmacKey,
 						}),
 					},
-					Data: map[string]string{
-						mountPath: string(content),
-					},
 				}
+				setConfigMapContent(configMap, mountPath, content)
 				resources.ConfigMaps = append(resources.ConfigMaps, configMap)
 			}
 
@@ -207,7 +211,7 @@ func (t Transformer) updatePodSpecWithVolumes(spec *corev1.PodSpec, service type
 
 			volumeMappings[serviceVolume.Source] = VolumeMapping{
 				Name:       volumeName,
-				ImageRef:   serviceVolume.Source,
+				ImageRef:   t.rewriteImage(serviceVolume.Source),
 				PullPolicy: pullPolicy,
 				IsImage:    true,
 			}
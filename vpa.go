@@ -0,0 +1,147 @@
+package kubepose
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/compose-spec/compose-go/v2/types"
+	"github.com/middle-management/kubepose/internal/vpa"
+	autoscalingv1 "k8s.io/api/autoscaling/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/utils/ptr"
+)
+
+// hasVerticalPodAutoscaler reports whether the service opts into a VPA.
+func hasVerticalPodAutoscaler(service types.ServiceConfig) bool {
+	_, ok := service.Annotations[VpaUpdateModeAnnotationKey]
+	return ok
+}
+
+func parseVpaUpdateMode(value string) (vpa.UpdateMode, error) {
+	for _, mode := range []vpa.UpdateMode{vpa.UpdateModeOff, vpa.UpdateModeInitial, vpa.UpdateModeRecreate, vpa.UpdateModeInPlaceOrRecreate, vpa.UpdateModeAuto} {
+		if strings.EqualFold(value, string(mode)) {
+			return mode, nil
+		}
+	}
+	return "", fmt.Errorf("%s %q: expected Off, Initial, Recreate, InPlaceOrRecreate or Auto", VpaUpdateModeAnnotationKey, value)
+}
+
+// validateVpaAnnotations rejects VPAs the converter cannot attach to a
+// workload, and VPAs that would fight a CPU-based HPA: the HPA scales on CPU
+// utilization relative to the request, which an acting VPA keeps changing.
+// A recommendation-only VPA (updateMode Off) changes nothing and is allowed.
+// Called from validateService.
+func validateVpaAnnotations(service types.ServiceConfig) error {
+	value, ok := service.Annotations[VpaUpdateModeAnnotationKey]
+	if !ok {
+		return nil
+	}
+	mode, err := parseVpaUpdateMode(value)
+	if err != nil {
+		return err
+	}
+	if _, isCronJob := service.Annotations[CronJobScheduleAnnotationKey]; isCronJob {
+		return fmt.Errorf("%s cannot be combined with %s (a VPA targets a Deployment or DaemonSet)", VpaUpdateModeAnnotationKey, CronJobScheduleAnnotationKey)
+	}
+	if isRunOnce(service) {
+		return fmt.Errorf("%s requires restart: always (the service converts to a standalone Pod, which a VPA cannot target)", VpaUpdateModeAnnotationKey)
+	}
+	if mode != vpa.UpdateModeOff && hasHorizontalPodAutoscaler(service) {
+		// Validated in validateHpaAnnotations, which runs first.
+		hpa, _ := getHpaExtension(service)
+		if hpaUsesCpu(service, hpa) {
+			return fmt.Errorf("%s %s cannot be combined with a CPU-based HPA (%s); use updateMode Off for recommendations only", VpaUpdateModeAnnotationKey, mode, HpaMaxReplicasAnnotationKey)
+		}
+	}
+	return nil
+}
+
+// createVerticalPodAutoscaler emits an autoscaling.k8s.io/v1 VPA targeting
+// the service's Deployment or DaemonSet. The compose reservations and limits
+// of every container in the pod bound its recommendations (minAllowed and
+// maxAllowed), so a VPA in an acting mode never sizes a container outside
+// what compose declared, including group members without the annotation.
+func (t Transformer) createVerticalPodAutoscaler(resources *Resources, service types.ServiceConfig, kind string, podSpec *corev1.PodSpec) {
+	serviceName := getServiceName(service)
+
+	// Grouped services share one workload and so one VPA, created by the
+	// first member asking for it.
+	for _, v := range resources.VerticalPodAutoscalers {
+		if v.ObjectMeta.Name == serviceName {
+			return
+		}
+	}
+
+	// Values are validated in validateService; parse errors cannot occur here.
+	mode, _ := parseVpaUpdateMode(service.Annotations[VpaUpdateModeAnnotationKey])
+
+	var resourcePolicy *vpa.PodResourcePolicy
+	if policies := vpaContainerPolicies(podSpec); len(policies) > 0 {
+		resourcePolicy = &vpa.PodResourcePolicy{ContainerPolicies: policies}
+	}
+
+	resources.VerticalPodAutoscalers = append(resources.VerticalPodAutoscalers, &vpa.VerticalPodAutoscaler{
+		TypeMeta: metav1.TypeMeta{
+			APIVersion: vpa.SchemeGroupVersion.String(),
+			Kind:       "VerticalPodAutoscaler",
+		},
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        serviceName,
+			Annotations: mergeMaps(service.Annotations, t.Annotations),
+			Labels:      mergeMaps(service.Labels, t.Labels),
+		},
+		Spec: vpa.VerticalPodAutoscalerSpec{
+			TargetRef: &autoscalingv1.CrossVersionObjectReference{
+				APIVersion: "apps/v1",
+				Kind:       kind,
+				Name:       serviceName,
+			},
+			UpdatePolicy:   &vpa.PodUpdatePolicy{UpdateMode: ptr.To(mode)},
+			ResourcePolicy: resourcePolicy,
+		},
+	})
+}
+
+// vpaContainerPolicies bounds each long-running container of the pod, app
+// containers and native sidecars, by its requests and limits. Run-once init
+// containers are not resized by a VPA.
+func vpaContainerPolicies(podSpec *corev1.PodSpec) []vpa.ContainerResourcePolicy {
+	var containers []corev1.Container
+	for _, container := range podSpec.InitContainers {
+		if container.RestartPolicy != nil && *container.RestartPolicy == corev1.ContainerRestartPolicyAlways {
+			containers = append(containers, container)
+		}
+	}
+	containers = append(containers, podSpec.Containers...)
+
+	var policies []vpa.ContainerResourcePolicy
+	for _, container := range containers {
+		policy := vpa.ContainerResourcePolicy{
+			ContainerName: container.Name,
+			MinAllowed:    vpaResources(container.Resources.Requests),
+			MaxAllowed:    vpaResources(container.Resources.Limits),
+		}
+		if policy.MinAllowed != nil || policy.MaxAllowed != nil {
+			policies = append(policies, policy)
+		}
+	}
+	return policies
+}
+
+// vpaResources keeps the CPU and memory entries of a resource list, the only
+// resources a VPA recommends.
+func vpaResources(list corev1.ResourceList) corev1.ResourceList {
+	var result corev1.ResourceList
+	for _, name := range []corev1.ResourceName{corev1.ResourceCPU, corev1.ResourceMemory} {
+		quantity, ok := list[name]
+		if !ok {
+			continue
+		}
+		if result == nil {
+			result = corev1.ResourceList{}
+		}
+		result[name] = quantity
+	}
+	return result
+}
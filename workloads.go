@@ -9,7 +9,19 @@ import (
 	"k8s.io/utils/ptr"
 )
 
-func (t Transformer) addContainersToSpec(podSpec *corev1.PodSpec, appServices, initServices []types.ServiceConfig) {
+// addContainersToSpec adds every group member's containers to the shared pod
+// spec, folding in pod-level settings members may declare individually.
+func (t Transformer) addContainersToSpec(podSpec *corev1.PodSpec, appServices, initServices []types.ServiceConfig) error {
+	for _, svc := range append(appServices, initServices...) {
+		if err := mergePodDNS(podSpec, svc); err != nil {
+			return err
+		}
+		mergePodNamespaces(podSpec, svc)
+		if err := mergePodSysctls(podSpec, svc); err != nil {
+			return err
+		}
+		t.mergePodImagePullSecrets(podSpec, svc)
+	}
 nextInitService:
 	for _, svc := range initServices {
 		for _, container := range podSpec.InitContainers {
@@ -31,6 +43,8 @@ nextService:
 		podSpec.InitContainers = append(podSpec.InitContainers, t.createPreStartContainers(svc)...)
 		podSpec.Containers = append(podSpec.Containers, t.createContainer(svc))
 	}
+	setPodOSForStopSignals(podSpec)
+	return nil
 }
 
 func (t Transformer) createPod(service types.ServiceConfig) *corev1.Pod {